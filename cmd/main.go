@@ -1,23 +1,50 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	_ "time/tzdata"
 
+	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/accentAm"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/boosty"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/bridge"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/cbr"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/config"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/documents"
 	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/dzen"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/edisclosure"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/filter"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/fundmanager"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/habr"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/jsonapi"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/okru"
 	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/rutube"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/scraper"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/smotrim"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/telegram"
 	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/vcru"
 	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/vkvideo"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/vkwall"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/web"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/yandexmusic"
 )
 
 // VK init
 var VK_TOKEN vkvideo.VkApiToken
 
+// guards VK_TOKEN, handlers refresh it concurrently
+var VK_TOKEN_MU sync.Mutex
+
 func prepareVkToken() {
 	for {
 		log.Println("Trying to obtain VK Video anonymous token")
@@ -28,35 +55,79 @@ func prepareVkToken() {
 			continue
 		}
 		log.Println("Got VK Video anonymous token")
+		VK_TOKEN_MU.Lock()
 		VK_TOKEN = vkToken
+		VK_TOKEN_MU.Unlock()
 		return
 	}
 }
 
+var CONFIG config.Config
+
+// built-in fund managers and the ones described in the config
+var FUND_MANAGERS []fundmanager.Manager
+
+// sites read with CSS selectors from the config
+var SCRAPER_SITES []scraper.Site
+
+// JSON APIs from the config
+var JSON_APIS []jsonapi.Api
+
 func init() {
+	loaded, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	CONFIG = loaded
+	FUND_MANAGERS = fundmanager.Managers(CONFIG.FundManagers)
+	SCRAPER_SITES = scraper.Sites(CONFIG.Scrapers)
+	JSON_APIS = jsonapi.Apis(CONFIG.JsonApis)
+
 	prepareVkToken()
 }
 
-func vkVideoRoute(c *gin.Context) {
-	username := strings.TrimSpace(c.Param("username"))
-	username = utils.StringsAllowlist(username, vkvideo.VALID_USERNAME_PATTERN)
+// feedRoute parses the shared item filter, builds the feed with getFeed,
+// filters it and renders it as RSS, or as a page with format=html.
+func feedRoute(getFeed func(c *gin.Context) (*utils.Feed, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		itemFilter, err := filter.Parse(c.Request.URL.Query())
+		if err != nil {
+			log.Println(err)
+			c.String(http.StatusBadRequest, "error")
+			return
+		}
 
-	skipBeforeStr := strings.TrimSpace(c.Query("skip_before"))
-	skipBeforeStr = utils.StringsAllowlist(skipBeforeStr, vkvideo.VALID_SKIP_BEFORE_PATTERN)
-	skipBefore := -1
-	if skipBeforeStr != "" {
-		parsed, err := strconv.Atoi(skipBeforeStr)
+		feed, err := getFeed(c)
 		if err != nil {
-			log.Printf("Got enormous int in skip_before = %s, defaulting to -1\n", skipBeforeStr)
-			parsed = -1
+			log.Println(err)
+			c.String(http.StatusBadRequest, "error")
+			return
 		}
-		skipBefore = parsed
-	}
 
-	if username == "" {
-		c.String(http.StatusBadRequest, "error")
-		return
+		itemFilter.Apply(feed)
+
+		if strings.TrimSpace(c.Query("format")) == "html" {
+			web.RenderFeed(c, web.NewFeedPage(feed, web.FeedUrl(c)))
+			return
+		}
+
+		feed.Stylesheet = web.STYLESHEET_PATH
+		rss, err := feed.ToRss()
+		if err != nil {
+			log.Println(err)
+			c.String(http.StatusBadRequest, "error")
+			return
+		}
+
+		c.Data(http.StatusOK, "application/xml; charset=utf-8", []byte(rss))
 	}
+}
+
+// vkToken returns the anonymous VK token, refreshing it shortly before it
+// expires. The request that triggers the refresh fails.
+func vkToken() (vkvideo.VkApiToken, error) {
+	VK_TOKEN_MU.Lock()
+	defer VK_TOKEN_MU.Unlock()
 
 	timeNow := time.Now().Unix()
 
@@ -66,97 +137,690 @@ func vkVideoRoute(c *gin.Context) {
 			log.Println(err)
 		}
 		VK_TOKEN = vkToken
+		return vkvideo.VkApiToken{}, fmt.Errorf("VK token expired")
+	}
+
+	return VK_TOKEN, nil
+}
+
+func vkVideoFeed(c *gin.Context) (*utils.Feed, error) {
+	username := strings.TrimSpace(c.Param("username"))
+	username = utils.StringsAllowlist(username, vkvideo.VALID_USERNAME_PATTERN)
+
+	liveFilter := strings.TrimSpace(c.Query("live"))
+	liveFilter = utils.StringsAllowlist(liveFilter, vkvideo.VALID_LIVE_FILTER_PATTERN)
+	if liveFilter != vkvideo.LIVE_FILTER_ALL &&
+		liveFilter != vkvideo.LIVE_FILTER_ONLY &&
+		liveFilter != vkvideo.LIVE_FILTER_EXCLUDE {
+		return nil, fmt.Errorf("invalid live filter %s", liveFilter)
+	}
+
+	if username == "" {
+		return nil, fmt.Errorf("empty username")
+	}
+
+	token, err := vkToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return vkvideo.GetFeed(username, token, liveFilter)
+}
+
+func vkVideoLookupFeedPath(u *url.URL) (string, bool, error) {
+	return vkvideo.LookupFeedPath(vkToken, u)
+}
+
+func vkWallFeed(c *gin.Context) (*utils.Feed, error) {
+	domain := strings.TrimSpace(c.Param("domain"))
+	domain = utils.StringsAllowlist(domain, vkwall.VALID_DOMAIN_PATTERN)
+	if domain == "" {
+		return nil, fmt.Errorf("empty domain")
+	}
+
+	noAds := strings.TrimSpace(c.Query("no_ads")) == "1"
+	noPinned := strings.TrimSpace(c.Query("no_pinned")) == "1"
+
+	token, err := vkToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return vkwall.GetFeed(domain, token, noAds, noPinned)
+}
+
+func dzenFeed(c *gin.Context) (*utils.Feed, error) {
+	username := strings.TrimSpace(c.Param("username"))
+	username = utils.StringsAllowlist(username, dzen.VALID_USERNAME_PATTERN)
+
+	typesStr := strings.TrimSpace(c.Query("type"))
+	typesStr = utils.StringsAllowlist(typesStr, dzen.VALID_TYPE_PATTERN)
+	types, err := dzen.ParseTypes(typesStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if username == "" {
+		return nil, fmt.Errorf("empty username")
+	}
+
+	fullText := strings.TrimSpace(c.Query("fulltext")) == "1"
+
+	return dzen.GetFeed(username, types, fullText)
+}
+
+func rutubeFeed(c *gin.Context) (*utils.Feed, error) {
+	channelId := strings.TrimSpace(c.Param("channel_id"))
+	channelId = utils.StringsAllowlist(channelId, rutube.VALID_CHANNEL_ID_PATTERN)
+
+	if channelId == "" || len(channelId) < 5 {
+		return nil, fmt.Errorf("invalid channel id %s", channelId)
+	}
+
+	return rutube.GetFeed(channelId)
+}
+
+func smotrimFeed(c *gin.Context) (*utils.Feed, error) {
+	brandId := strings.TrimSpace(c.Param("brand_id"))
+	brandId = utils.StringsAllowlist(brandId, smotrim.VALID_BRAND_ID_PATTERN)
+	if brandId == "" {
+		return nil, fmt.Errorf("empty brand id")
+	}
+
+	return smotrim.GetFeed(brandId)
+}
+
+func okruFeed(c *gin.Context) (*utils.Feed, error) {
+	channelId := strings.TrimSpace(c.Param("channel_id"))
+	channelId = utils.StringsAllowlist(channelId, okru.VALID_CHANNEL_ID_PATTERN)
+	if channelId == "" {
+		return nil, fmt.Errorf("empty channel id")
+	}
+
+	return okru.GetFeed(channelId)
+}
+
+func telegramFeed(c *gin.Context) (*utils.Feed, error) {
+	channel := strings.TrimSpace(c.Param("channel"))
+	channel = utils.StringsAllowlist(channel, telegram.VALID_CHANNEL_PATTERN)
+	if channel == "" {
+		return nil, fmt.Errorf("empty channel")
+	}
+
+	return telegram.GetFeed(channel)
+}
+
+func boostyFeed(c *gin.Context) (*utils.Feed, error) {
+	blog := strings.TrimSpace(c.Param("blog"))
+	blog = utils.StringsAllowlist(blog, boosty.VALID_BLOG_PATTERN)
+	if blog == "" {
+		return nil, fmt.Errorf("empty blog")
+	}
+
+	noLocked := strings.TrimSpace(c.Query("no_locked")) == "1"
+
+	return boosty.GetFeed(blog, noLocked)
+}
+
+func habrFeed(kind string) func(c *gin.Context) (*utils.Feed, error) {
+	return func(c *gin.Context) (*utils.Feed, error) {
+		name := strings.TrimSpace(c.Param("name"))
+		name = utils.StringsAllowlist(name, habr.VALID_NAME_PATTERN)
+		if name == "" {
+			return nil, fmt.Errorf("empty name")
+		}
+
+		return habr.GetFeed(kind, name)
+	}
+}
+
+func vcruFeed(kind string) func(c *gin.Context) (*utils.Feed, error) {
+	return func(c *gin.Context) (*utils.Feed, error) {
+		name := strings.TrimSpace(c.Param("name"))
+		name = utils.StringsAllowlist(name, vcru.VALID_NAME_PATTERN)
+		if name == "" {
+			return nil, fmt.Errorf("empty name")
+		}
+
+		return vcru.GetFeed(kind, name)
+	}
+}
+
+func eDisclosureFeed(c *gin.Context) (*utils.Feed, error) {
+	companyId := strings.TrimSpace(c.Param("company_id"))
+	companyId = utils.StringsAllowlist(companyId, edisclosure.VALID_COMPANY_ID_PATTERN)
+	if companyId == "" {
+		return nil, fmt.Errorf("empty company id")
+	}
+
+	kindsStr := strings.TrimSpace(c.Query("type"))
+	kindsStr = utils.StringsAllowlist(kindsStr, edisclosure.VALID_KIND_PATTERN)
+	kinds, err := edisclosure.ParseKinds(kindsStr)
+	if err != nil {
+		return nil, err
+	}
+
+	categoriesStr := strings.TrimSpace(c.Query("category"))
+	categoriesStr = utils.StringsAllowlist(categoriesStr, edisclosure.VALID_CATEGORY_PATTERN)
+
+	return edisclosure.GetFeed(companyId, kinds, edisclosure.ParseCategories(categoriesStr))
+}
+
+func cbrFeed(c *gin.Context) (*utils.Feed, error) {
+	ratesStr := strings.TrimSpace(c.Query("rates"))
+	ratesStr = utils.StringsAllowlist(ratesStr, cbr.VALID_CURRENCIES_PATTERN)
+	keyRateOnly := strings.TrimSpace(c.Query("key_rate")) == "1"
+
+	return cbr.GetFeed(cbr.ParseCurrencies(ratesStr), keyRateOnly)
+}
+
+func yandexMusicFeed(c *gin.Context) (*utils.Feed, error) {
+	albumId := strings.TrimSpace(c.Param("album_id"))
+	albumId = utils.StringsAllowlist(albumId, yandexmusic.VALID_ID_PATTERN)
+	if albumId == "" {
+		return nil, fmt.Errorf("empty album id")
+	}
+
+	baseUrl := web.BaseUrl(c)
+	return yandexmusic.GetFeed(albumId, func(trackId string) string {
+		return fmt.Sprintf("%s/yandex-music/track/%s.mp3", baseUrl, trackId)
+	})
+}
+
+// yandexMusicTrackRoute redirects to a freshly signed mp3 link, the ones
+// from the API expire too soon to be put in a feed.
+func yandexMusicTrackRoute(c *gin.Context) {
+	trackId := strings.TrimSuffix(strings.TrimSpace(c.Param("track_id")), ".mp3")
+	trackId = utils.StringsAllowlist(trackId, yandexmusic.VALID_ID_PATTERN)
+	if trackId == "" {
 		c.String(http.StatusBadRequest, "error")
 		return
 	}
 
-	feed, err := vkvideo.GetFeed(username, VK_TOKEN, skipBefore)
+	trackUrl, err := yandexmusic.GetTrackUrl(trackId)
 	if err != nil {
 		log.Println(err)
 		c.String(http.StatusBadRequest, "error")
 		return
 	}
 
-	c.String(http.StatusOK, feed)
+	c.Redirect(http.StatusFound, trackUrl)
 }
 
-func dzenRoute(c *gin.Context) {
-	username := strings.TrimSpace(c.Param("username"))
-	username = utils.StringsAllowlist(username, vkvideo.VALID_USERNAME_PATTERN)
+func accentAmParams(c *gin.Context) (string, int) {
+	section := strings.TrimSpace(c.Query("section"))
+	section = utils.StringsAllowlist(section, accentAm.VALID_SECTION_PATTERN)
+	if section == "" {
+		section = accentAm.DEFAULT_SECTION
+	}
 
-	skipBeforeStr := strings.TrimSpace(c.Query("skip_before"))
-	skipBeforeStr = utils.StringsAllowlist(skipBeforeStr, vkvideo.VALID_SKIP_BEFORE_PATTERN)
-	skipBefore := -1
-	if skipBeforeStr != "" {
-		parsed, err := strconv.Atoi(skipBeforeStr)
+	paragraphsStr := strings.TrimSpace(c.Query("paragraphs"))
+	paragraphsStr = utils.StringsAllowlist(paragraphsStr, accentAm.VALID_PARAGRAPHS_PATTERN)
+	paragraphs := documents.DEFAULT_PARAGRAPHS
+	if paragraphsStr != "" {
+		parsed, err := strconv.Atoi(paragraphsStr)
 		if err != nil {
-			log.Printf("Got enormous int in skip_before = %s, defaulting to -1\n", skipBeforeStr)
-			parsed = -1
+			log.Printf("Got enormous int in paragraphs = %s, defaulting to %d\n", paragraphsStr, paragraphs)
+			parsed = paragraphs
 		}
-		skipBefore = parsed
+		paragraphs = parsed
 	}
 
-	if username == "" {
+	return section, paragraphs
+}
+
+func accentAmFeed(c *gin.Context) (*utils.Feed, error) {
+	fundName := strings.TrimSpace(c.Param("fund_name"))
+	fundName = utils.StringsAllowlist(fundName, accentAm.VALID_FUND_PATTERN)
+	if fundName == "" || len(fundName) < 5 {
+		return nil, fmt.Errorf("invalid fund name %s", fundName)
+	}
+
+	// the same items as /fund-manager/accent-am/<fund_name>
+	section, paragraphs := fundManagerParams(c)
+	return fundmanager.GetFeed(fundmanager.ACCENT_AM, fundName, section, paragraphs)
+}
+
+func accentAmAllFeed(c *gin.Context) (*utils.Feed, error) {
+	section, paragraphs := accentAmParams(c)
+	return accentAm.GetAggregateFeed(section, paragraphs)
+}
+
+func fundManagerParams(c *gin.Context) (string, int) {
+	// an empty section leaves the choice to the manager
+	section := strings.TrimSpace(c.Query("section"))
+	section = utils.StringsAllowlist(section, fundmanager.VALID_SECTION_PATTERN)
+
+	paragraphsStr := strings.TrimSpace(c.Query("paragraphs"))
+	paragraphsStr = utils.StringsAllowlist(paragraphsStr, fundmanager.VALID_PARAGRAPHS_PATTERN)
+	paragraphs := documents.DEFAULT_PARAGRAPHS
+	if paragraphsStr != "" {
+		parsed, err := strconv.Atoi(paragraphsStr)
+		if err != nil {
+			log.Printf("Got enormous int in paragraphs = %s, defaulting to %d\n", paragraphsStr, paragraphs)
+			parsed = paragraphs
+		}
+		paragraphs = parsed
+	}
+
+	return section, paragraphs
+}
+
+func fundManagerFeed(c *gin.Context) (*utils.Feed, error) {
+	name := strings.TrimSpace(c.Param("manager"))
+	name = utils.StringsAllowlist(name, utils.VALID_NAME_PATTERN)
+	manager, ok := utils.FindByName(FUND_MANAGERS, name)
+	if !ok {
+		return nil, fmt.Errorf("unknown fund manager %s", name)
+	}
+
+	fundName := strings.TrimSpace(c.Param("fund_name"))
+	fundName = utils.StringsAllowlist(fundName, fundmanager.VALID_FUND_PATTERN)
+	if fundName == "" {
+		return nil, fmt.Errorf("empty fund name")
+	}
+
+	section, paragraphs := fundManagerParams(c)
+	return fundmanager.GetFeed(manager, fundName, section, paragraphs)
+}
+
+func scraperFeed(c *gin.Context) (*utils.Feed, error) {
+	name := strings.TrimSpace(c.Param("site"))
+	name = utils.StringsAllowlist(name, utils.VALID_NAME_PATTERN)
+	site, ok := utils.FindByName(SCRAPER_SITES, name)
+	if !ok {
+		return nil, fmt.Errorf("unknown site %s", name)
+	}
+
+	return scraper.GetFeed(site, c.Request.URL.Query())
+}
+
+func jsonApiFeed(c *gin.Context) (*utils.Feed, error) {
+	name := strings.TrimSpace(c.Param("api"))
+	name = utils.StringsAllowlist(name, utils.VALID_NAME_PATTERN)
+	api, ok := utils.FindByName(JSON_APIS, name)
+	if !ok {
+		return nil, fmt.Errorf("unknown api %s", name)
+	}
+
+	return jsonapi.GetFeed(api, c.Request.URL.Query())
+}
+
+func accentAmSectionsRoute(c *gin.Context) {
+	fundName := strings.TrimSpace(c.Param("fund_name"))
+	fundName = utils.StringsAllowlist(fundName, accentAm.VALID_FUND_PATTERN)
+	if fundName == "" || len(fundName) < 5 {
+		c.String(http.StatusBadRequest, "error")
+		return
+	}
+	sections, err := accentAm.GetSections(fundName)
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusBadRequest, "error")
+		return
+	}
+
+	c.JSON(http.StatusOK, sections)
+}
+
+func accentAmFundsRoute(c *gin.Context) {
+	funds, err := accentAm.GetFunds()
+	if err != nil {
+		log.Println(err)
 		c.String(http.StatusBadRequest, "error")
 		return
 	}
 
-	feed, err := dzen.GetFeed(username, skipBefore)
+	if strings.TrimSpace(c.Query("format")) != "opml" {
+		c.JSON(http.StatusOK, funds)
+		return
+	}
+
+	var outlines []utils.OpmlOutline
+	for _, fund := range funds {
+		xmlUrl := fmt.Sprintf("%s/accent-am/%s", web.BaseUrl(c), fund.Slug)
+		outlines = append(outlines, utils.NewRssOutline(fund.Name, xmlUrl, fund.URL))
+	}
+
+	opml, err := utils.NewOpml("Фонды акцент", outlines).ToXML()
 	if err != nil {
 		log.Println(err)
 		c.String(http.StatusBadRequest, "error")
 		return
 	}
 
-	c.String(http.StatusOK, feed)
+	c.Data(http.StatusOK, "text/x-opml; charset=utf-8", []byte(opml))
 }
 
-func rutubeRoute(c *gin.Context) {
-	channelId := strings.TrimSpace(c.Param("channel_id"))
-	channelId = utils.StringsAllowlist(channelId, rutube.VALID_CHANNEL_ID_PATTERN)
+var BRIDGES = []bridge.Bridge{
+	{
+		Name:           "vkvideo",
+		Title:          "VK Video",
+		Description:    "Latest videos and broadcasts of a VK Video channel.",
+		Path:           "/vkvideo/:username",
+		Params:         vkvideo.PARAMS,
+		GetFeed:        vkVideoFeed,
+		FeedPath:       vkvideo.FeedPath,
+		LookupFeedPath: vkVideoLookupFeedPath,
+	},
+	{
+		Name:        "vkwall",
+		Title:       "VK wall",
+		Description: "Posts from the wall of a VK community or user, with photos, links and reposts.",
+		Path:        "/vkwall/:domain",
+		Params:      vkwall.PARAMS,
+		GetFeed:     vkWallFeed,
+		FeedPath:    vkwall.FeedPath,
+	},
+	{
+		Name:           "dzen",
+		Title:          "Dzen",
+		Description:    "Articles, posts, videos and shorts of a Dzen channel.",
+		Path:           "/dzen/:username",
+		Params:         dzen.PARAMS,
+		GetFeed:        dzenFeed,
+		FeedPath:       dzen.FeedPath,
+		LookupFeedPath: dzen.LookupFeedPath,
+	},
+	{
+		Name:           "rutube",
+		Title:          "Rutube",
+		Description:    "Latest videos of a Rutube channel.",
+		Path:           "/rutube/:channel_id",
+		Params:         rutube.PARAMS,
+		GetFeed:        rutubeFeed,
+		FeedPath:       rutube.FeedPath,
+		LookupFeedPath: rutube.LookupFeedPath,
+	},
+	{
+		Name:        "smotrim",
+		Title:       "Smotrim",
+		Description: "Latest episodes of a smotrim.ru programme.",
+		Path:        "/smotrim/:brand_id",
+		Params:      smotrim.PARAMS,
+		GetFeed:     smotrimFeed,
+		FeedPath:    smotrim.FeedPath,
+	},
+	{
+		Name:        "okru",
+		Title:       "OK Video",
+		Description: "Latest videos of an OK.ru video channel.",
+		Path:        "/okru/:channel_id",
+		Params:      okru.PARAMS,
+		GetFeed:     okruFeed,
+		FeedPath:    okru.FeedPath,
+	},
+	{
+		Name:        "yandex-music",
+		Title:       "Yandex Music podcast",
+		Description: "Episodes of a Yandex Music podcast as a podcast feed with audio enclosures.",
+		Path:        "/yandex-music/:album_id",
+		Params:      yandexmusic.PARAMS,
+		GetFeed:     yandexMusicFeed,
+		FeedPath:    yandexmusic.FeedPath,
+	},
+	{
+		Name:        "telegram",
+		Title:       "Telegram",
+		Description: "Messages of a public Telegram channel, from its t.me/s/ web preview.",
+		Path:        "/telegram/:channel",
+		Params:      telegram.PARAMS,
+		GetFeed:     telegramFeed,
+		FeedPath:    telegram.FeedPath,
+	},
+	{
+		Name:        "boosty",
+		Title:       "Boosty",
+		Description: "Posts of a Boosty blog, with teasers of the ones available only to subscribers.",
+		Path:        "/boosty/:blog",
+		Params:      boosty.PARAMS,
+		GetFeed:     boostyFeed,
+		FeedPath:    boosty.FeedPath,
+	},
+	{
+		Name:        "habr-user",
+		Title:       "Habr author",
+		Description: "Full-text articles of a Habr author, with hubs, rating and comment counts.",
+		Path:        "/habr/user/:name",
+		Params:      habr.USER_PARAMS,
+		GetFeed:     habrFeed(habr.KIND_USER),
+		FeedPath:    habr.FeedPath,
+	},
+	{
+		Name:        "habr-hub",
+		Title:       "Habr hub",
+		Description: "Full-text articles of a Habr hub, with hubs, rating and comment counts.",
+		Path:        "/habr/hub/:name",
+		Params:      habr.HUB_PARAMS,
+		GetFeed:     habrFeed(habr.KIND_HUB),
+		FeedPath:    habr.FeedPath,
+	},
+	{
+		Name:        "vcru-author",
+		Title:       "VC.ru author",
+		Description: "Full-text articles of a VC.ru author, with tags, rating and comment counts.",
+		Path:        "/vcru/u/:name",
+		Params:      vcru.AUTHOR_PARAMS,
+		GetFeed:     vcruFeed(vcru.KIND_AUTHOR),
+		FeedPath:    vcru.FeedPath,
+	},
+	{
+		Name:        "vcru",
+		Title:       "VC.ru section",
+		Description: "Full-text articles of a VC.ru section, with tags, rating and comment counts.",
+		Path:        "/vcru/:name",
+		Params:      vcru.SECTION_PARAMS,
+		GetFeed:     vcruFeed(vcru.KIND_SECTION),
+		FeedPath:    vcru.FeedPath,
+	},
+	{
+		Name:        "e-disclosure",
+		Title:       "E-disclosure",
+		Description: "Events and documents a company discloses on e-disclosure.ru, sorted into categories.",
+		Path:        "/e-disclosure/:company_id",
+		Params:      edisclosure.PARAMS,
+		GetFeed:     eDisclosureFeed,
+		FeedPath:    edisclosure.FeedPath,
+	},
+	{
+		Name:        "cbr",
+		Title:       "Bank of Russia",
+		Description: "Press releases and key rate decisions of the Bank of Russia, with a daily item of official exchange rates.",
+		Path:        "/cbr",
+		Params:      cbr.PARAMS,
+		GetFeed:     cbrFeed,
+		FeedPath:    cbr.FeedPath,
+	},
+	{
+		Name:        "accent-am-all",
+		Title:       "Accent AM, all funds",
+		Description: "Documents of every Accent AM fund in one feed.",
+		Path:        "/accent-am/all",
+		Params:      accentAm.AGGREGATE_PARAMS,
+		GetFeed:     accentAmAllFeed,
+	},
+	{
+		Name:        "accent-am",
+		Title:       "Accent AM",
+		Description: "Documents published by an Accent AM fund.",
+		Path:        "/accent-am/:fund_name",
+		Params:      accentAm.PARAMS,
+		GetFeed:     accentAmFeed,
+		FeedPath:    accentAm.FeedPath,
+	},
+	{
+		Name:        "fund-manager",
+		Title:       "Fund manager",
+		Description: "Documents of a mutual fund of Accent AM or of a management company from the config.",
+		Path:        "/fund-manager/:manager/:fund_name",
+		Params:      fundmanager.PARAMS,
+		GetFeed:     fundManagerFeed,
+	},
+	{
+		Name:        "scrape",
+		Title:       "Scraped site",
+		Description: "Items of a page from the config, found with CSS selectors.",
+		Path:        "/scrape/:site",
+		Params:      scraper.PARAMS,
+		GetFeed:     scraperFeed,
+	},
+	{
+		Name:        "json",
+		Title:       "JSON API",
+		Description: "Items of a JSON API from the config, mapped to feed fields with JSONPath.",
+		Path:        "/json/:api",
+		Params:      jsonapi.PARAMS,
+		GetFeed:     jsonApiFeed,
+	},
+}
 
-	skipBeforeStr := strings.TrimSpace(c.Query("skip_before"))
-	skipBeforeStr = utils.StringsAllowlist(skipBeforeStr, rutube.VALID_SKIP_BEFORE_PATTERN)
-	skipBefore := -1
-	if skipBeforeStr != "" {
-		parsed, err := strconv.Atoi(skipBeforeStr)
+// mergeFeed combines the bridge routes given in feed= into one feed, e.g.
+// /merge?feed=/vkvideo/user&feed=/rutube/12345
+func mergeFeed(c *gin.Context) (*utils.Feed, error) {
+	window := bridge.DEFAULT_MERGE_WINDOW
+	if windowStr := strings.TrimSpace(c.Query("window")); windowStr != "" {
+		parsed, err := filter.ParseDuration(windowStr)
 		if err != nil {
-			log.Printf("Got enormous int in skip_before = %s, defaulting to -1\n", skipBeforeStr)
-			parsed = -1
+			return nil, err
 		}
-		skipBefore = parsed
+		window = parsed
 	}
 
-	if channelId == "" || len(channelId) < 5 {
+	return bridge.MergeFeeds(BRIDGES, c.QueryArray("feed"), window)
+}
+
+// opmlExportRoute lists the feeds from the config, grouped by bridge. Feeds
+// requested by clients are not exported, they are nobody else's business.
+func opmlExportRoute(c *gin.Context) {
+	var groups []utils.OpmlOutline
+	groupIndex := map[string]int{}
+	seenSet := mapset.NewSet[string]()
+	for _, feed := range CONFIG.Feeds {
+		if seenSet.Contains(feed.Path) {
+			continue
+		}
+		seenSet.Add(feed.Path)
+
+		routePath := strings.SplitN(feed.Path, "?", 2)[0]
+		group := ""
+		if b, _, ok := bridge.Find(BRIDGES, routePath); ok {
+			group = b.Name
+		} else if strings.TrimSuffix(routePath, "/") == "/merge" {
+			group = "merge"
+		} else {
+			log.Printf("opml: skipping %s, no bridge serves it\n", feed.Path)
+			continue
+		}
+		if _, ok := groupIndex[group]; !ok {
+			groupIndex[group] = len(groups)
+			groups = append(groups, utils.OpmlOutline{Text: group, Title: group})
+		}
+
+		title := feed.Title
+		if title == "" {
+			title = feed.Path
+		}
+		outline := utils.NewRssOutline(title, web.BaseUrl(c)+feed.Path, "")
+		groups[groupIndex[group]].Outlines = append(groups[groupIndex[group]].Outlines, outline)
+	}
+
+	opml, err := utils.NewOpml("go-rss-bridge", groups).ToXML()
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusBadRequest, "error")
+		return
+	}
+
+	c.Data(http.StatusOK, "text/x-opml; charset=utf-8", []byte(opml))
+}
+
+type importResult struct {
+	Url   string `json:"url"`
+	Feed  string `json:"feed,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// opmlImportRoute takes an OPML document or a plain list of links to the
+// source sites and answers with the matching bridge feeds.
+func opmlImportRoute(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		log.Println(err)
 		c.String(http.StatusBadRequest, "error")
 		return
 	}
 
-	feed, err := rutube.GetFeed(channelId, skipBefore)
+	var urls []string
+	if opml, err := utils.ParseOpml(body); err == nil {
+		urls = opml.Urls()
+	} else {
+		for _, line := range strings.Split(string(body), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				urls = append(urls, line)
+			}
+		}
+	}
+
+	if len(urls) > bridge.MAX_IMPORTED_URLS {
+		urls = urls[:bridge.MAX_IMPORTED_URLS]
+	}
+
+	var results []importResult
+	var outlines []utils.OpmlOutline
+	lookups := 0
+	for _, sourceUrl := range urls {
+		path, err := bridge.ResolveOffline(BRIDGES, sourceUrl)
+		// links that need a request to the site share a small budget
+		if err != nil && lookups < bridge.MAX_IMPORT_LOOKUPS {
+			lookups++
+			path, err = bridge.Resolve(BRIDGES, sourceUrl)
+		}
+		if err != nil {
+			results = append(results, importResult{Url: sourceUrl, Error: err.Error()})
+			continue
+		}
+		results = append(results, importResult{Url: sourceUrl, Feed: web.BaseUrl(c) + path})
+		outlines = append(outlines, utils.NewRssOutline(path, web.BaseUrl(c)+path, sourceUrl))
+	}
+
+	if strings.TrimSpace(c.Query("format")) == "json" {
+		c.JSON(http.StatusOK, results)
+		return
+	}
+
+	opml, err := utils.NewOpml("go-rss-bridge", outlines).ToXML()
 	if err != nil {
 		log.Println(err)
 		c.String(http.StatusBadRequest, "error")
 		return
 	}
 
-	c.String(http.StatusOK, feed)
+	c.Data(http.StatusOK, "text/x-opml; charset=utf-8", []byte(opml))
 }
 
-func accentAmRoute(c *gin.Context) {
-	fundName := strings.TrimSpace(c.Param("fund_name"))
-	fundName = utils.StringsAllowlist(fundName, accentAm.VALID_FUND_PATTERN)
-	if fundName == "" || len(fundName) < 5 {
+// resolveRoute turns a link to a supported site into the bridge feed URL,
+// e.g. /resolve?url=https://rutube.ru/video/abc/
+func resolveRoute(c *gin.Context) {
+	sourceUrl := strings.TrimSpace(c.Query("url"))
+	if sourceUrl == "" {
 		c.String(http.StatusBadRequest, "error")
 		return
 	}
-	feed, err := accentAm.GetFeed(fundName)
+
+	path, err := bridge.Resolve(BRIDGES, sourceUrl)
 	if err != nil {
 		log.Println(err)
-		c.String(http.StatusBadRequest, "error")
+		c.String(http.StatusNotFound, "error")
 		return
 	}
 
-	c.String(http.StatusOK, feed)
+	feedUrl := web.BaseUrl(c) + path
+	if strings.TrimSpace(c.Query("redirect")) == "1" {
+		c.Redirect(http.StatusFound, feedUrl)
+		return
+	}
+
+	c.JSON(http.StatusOK, importResult{Url: sourceUrl, Feed: feedUrl})
 }
 
 func main() {
@@ -165,10 +829,19 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
-	router.GET("/vkvideo/:username", vkVideoRoute)
-	router.GET("/dzen/:username", dzenRoute)
-	router.GET("/rutube/:channel_id", rutubeRoute)
-	router.GET("/accent-am/:fund_name", accentAmRoute)
+	for _, b := range BRIDGES {
+		router.GET(b.Path, feedRoute(b.GetFeed))
+	}
+	router.GET("/merge", feedRoute(mergeFeed))
+	router.GET("/opml", opmlExportRoute)
+	router.POST("/opml", opmlImportRoute)
+	router.GET("/resolve", resolveRoute)
+	router.GET("/", web.IndexRoute(BRIDGES))
+	router.GET(web.STYLESHEET_PATH, web.StylesheetRoute)
+	router.GET("/preview", web.PreviewRoute(BRIDGES))
+	router.GET("/accent-am", accentAmFundsRoute)
+	router.GET("/accent-am/:fund_name/sections", accentAmSectionsRoute)
+	router.GET("/yandex-music/track/:track_id", yandexMusicTrackRoute)
 
 	log.Fatal(router.Run(":8080"))
 }
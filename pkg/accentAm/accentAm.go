@@ -3,7 +3,7 @@ package accentAm
 import (
 	"fmt"
 	"log"
-	"net/http"
+	"net/url"
 	"strings"
 	"time"
 	"unicode"
@@ -11,9 +11,8 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/documents"
 	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
-	// mapset "github.com/deckarep/golang-set/v2"
-	// "github.com/gorilla/feeds"
 )
 
 // https://accent-am.ru/funds/aktsent-5-fond-nedvizhimosti
@@ -26,156 +25,304 @@ var VALID_FUND_PATTERN = []*unicode.RangeTable{
 	{R16: []unicode.Range16{{'.', '.', 1}}},
 }
 
+var VALID_PARAGRAPHS_PATTERN = []*unicode.RangeTable{
+	unicode.Digit,
+}
+
+var VALID_SECTION_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	unicode.White_Space,
+	{R16: []unicode.Range16{{'-', '-', 1}}},
+}
+
 const BASE_URL = "https://accent-am.ru"
 
+const SOURCE_NAME = "accent-am"
+const SOURCE_TIMEZONE = utils.MOSCOW_TIMEZONE
+
+const DEFAULT_SECTION = "Сообщения"
+const SECTION_ALL = "all"
+
+var PARAMS = []utils.Param{
+	{Name: "fund_name", Description: "Fund name from accent-am.ru/funds/name", Example: "aktsent-5-fond-nedvizhimosti", Path: true},
+	{Name: "section", Description: "Part of a document tab name, or all", Example: DEFAULT_SECTION},
+	{Name: "paragraphs", Description: "Paragraphs of document text to include, 0 to skip downloading documents", Example: "3"},
+}
+
+var AGGREGATE_PARAMS = PARAMS[1:]
+
 type Message struct {
 	Title        string
 	URL          string
 	DateRaw      string
 	Date         time.Time
 	Availability string
+	Section      string
+}
+
+type Fund struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+const AGGREGATE_WORKERS = 4
+
+var fundsCache = utils.NewCache[[]Fund](time.Hour, 1)
+
+type Section struct {
+	Name  string `json:"name"`
+	TabID string `json:"-"`
 }
 
 func parseDate(s string) (time.Time, error) {
 	layout := "02.01.2006, 15:04"
-	return time.Parse(layout, strings.TrimSpace(s))
+	return time.ParseInLocation(layout, strings.TrimSpace(s), utils.SourceLocation(SOURCE_NAME, SOURCE_TIMEZONE))
 }
 
-func GetLatestMessagesByFundName(fundName string) ([]Message, error) {
-	url := fmt.Sprintf("%s/funds/%s", BASE_URL, fundName)
+func fetchFundPage(fundName string) (*goquery.Document, error) {
+	return utils.FetchDocument(fmt.Sprintf("%s/funds/%s", BASE_URL, fundName))
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Println(err)
-		return []Message{}, err
+func findSections(doc *goquery.Document) []Section {
+	var sections []Section
+	doc.Find(".fund-documents__list__links a").Each(func(_ int, s *goquery.Selection) {
+		tabID, exists := s.Attr("aria-controls")
+		if !exists {
+			return
+		}
+		sections = append(sections, Section{
+			Name:  strings.TrimSpace(s.Text()),
+			TabID: tabID,
+		})
+	})
+	return sections
+}
+
+// matchSection accepts "all" or a case-insensitive part of the tab name.
+func matchSection(section Section, query string) bool {
+	if query == SECTION_ALL {
+		return true
 	}
+	return strings.Contains(strings.ToLower(section.Name), strings.ToLower(query))
+}
 
-	req.Header.Set("User-Agent", utils.USER_AGENT)
+func parseDocuments(list *goquery.Selection, section string) []Message {
+	var results []Message
+	list.Find("ul.fund-documents__documents li").Each(func(_ int, li *goquery.Selection) {
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Println(err)
-		return []Message{}, err
-	}
-	defer resp.Body.Close()
+		a := li.Find("a.document-item")
+		if a.Length() == 0 {
+			return // skip header row
+		}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		log.Println(err)
-		return []Message{}, err
-	}
+		title := strings.TrimSpace(a.Find(".document-item__title").Text())
+		url, _ := a.Attr("href")
+		url = strings.TrimPrefix(url, "/")
+		if url == "" {
+			return
+		}
 
+		availability := strings.TrimSpace(
+			a.Find(".document-item__info__text span:last-child").Text(),
+		)
+
+		dateStr := strings.TrimSpace(
+			a.Find(".document-item__info__date span:last-child").Text(),
+		)
+
+		parsedDate, err := parseDate(dateStr)
+		if err != nil {
+			log.Printf("failed to parse date: %s (%v)", dateStr, err)
+		}
+
+		results = append(results, Message{
+			Title:        title,
+			URL:          fmt.Sprintf("%s/%s", BASE_URL, url),
+			DateRaw:      dateStr,
+			Date:         parsedDate,
+			Availability: availability,
+			Section:      section,
+		})
+	})
+	return results
+}
+
+func parseSection(doc *goquery.Document, section Section) []Message {
 	var results []Message
 
-	// 1. Find tab button "Сообщения"
-	doc.Find(".fund-documents__list__links a").Each(func(i int, s *goquery.Selection) {
-		if strings.Contains(strings.TrimSpace(s.Text()), "Сообщения") {
-			tabID, exists := s.Attr("aria-controls")
-			if !exists {
-				return
+	// 1. Find corresponding tab panel
+	panel := doc.Find(fmt.Sprintf("#%s", section.TabID))
+
+	// 2. Find "За все года" tab, sections without year tabs are read as is
+	panel.Find(".fund-documents__content").Each(func(_ int, content *goquery.Selection) {
+
+		var allTabID string
+
+		content.Find("[role='tab']").Each(func(_ int, tab *goquery.Selection) {
+			if strings.Contains(tab.Text(), "За все года") {
+				id, ok := tab.Attr("aria-controls")
+				if ok {
+					allTabID = id
+				}
 			}
+		})
 
-			// 2. Find corresponding tab panel
-			panel := doc.Find(fmt.Sprintf("#%s", tabID))
+		if allTabID == "" {
+			results = append(results, parseDocuments(content, section.Name)...)
+			return
+		}
 
-			// 3. Find "За все года" tab
-			panel.Find(".fund-documents__content").Each(func(_ int, content *goquery.Selection) {
+		// 3. Find content for "За все года"
+		allPanel := content.Find(fmt.Sprintf("#%s", allTabID))
+		results = append(results, parseDocuments(allPanel, section.Name)...)
+	})
+	return results
+}
 
-				var allTabID string
+// GetFunds scrapes the funds listing for every fund page it links to.
+func GetFunds() ([]Fund, error) {
+	if funds, ok := fundsCache.Get(BASE_URL); ok {
+		return funds, nil
+	}
 
-				content.Find("[role='tab']").Each(func(_ int, tab *goquery.Selection) {
-					if strings.Contains(tab.Text(), "За все года") {
-						id, ok := tab.Attr("aria-controls")
-						if ok {
-							allTabID = id
-						}
-					}
-				})
+	doc, err := utils.FetchDocument(fmt.Sprintf("%s/funds", BASE_URL))
+	if err != nil {
+		return []Fund{}, err
+	}
 
-				if allTabID == "" {
-					return
-				}
+	var funds []Fund
+	seenSet := mapset.NewSet[string]()
+	doc.Find(`a[href*="/funds/"]`).Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		href = strings.TrimPrefix(href, BASE_URL)
+		slug := strings.Trim(strings.TrimPrefix(href, "/funds/"), "/")
+		if slug == "" || strings.ContainsAny(slug, "/?#") || seenSet.Contains(slug) {
+			return
+		}
 
-				// 4. Find content for "За все года"
-				allPanel := content.Find(fmt.Sprintf("#%s", allTabID))
-
-				allPanel.Find("ul.fund-documents__documents li").Each(func(_ int, li *goquery.Selection) {
-
-					a := li.Find("a.document-item")
-					if a.Length() == 0 {
-						return // skip header row
-					}
-
-					title := strings.TrimSpace(a.Find(".document-item__title").Text())
-					url, _ := a.Attr("href")
-					if url[0] == '/' {
-						url = url[1:]
-					}
-
-					availability := strings.TrimSpace(
-						a.Find(".document-item__info__text span:last-child").Text(),
-					)
-
-					dateStr := strings.TrimSpace(
-						a.Find(".document-item__info__date span:last-child").Text(),
-					)
-
-					parsedDate, err := parseDate(dateStr)
-					if err != nil {
-						log.Printf("failed to parse date: %s (%v)", dateStr, err)
-					}
-
-					results = append(results, Message{
-						Title:        title,
-						URL:          fmt.Sprintf("%s/%s", BASE_URL, url),
-						DateRaw:      dateStr,
-						Date:         parsedDate,
-						Availability: availability,
-					})
-				})
-			})
+		name := strings.TrimSpace(a.Find("[class*='title']").First().Text())
+		if name == "" {
+			name = strings.TrimSpace(a.Text())
+		}
+		if name == "" {
+			return
 		}
+		name = strings.Join(strings.Fields(name), " ")
+
+		seenSet.Add(slug)
+		funds = append(funds, Fund{
+			Slug: slug,
+			Name: name,
+			URL:  fmt.Sprintf("%s/funds/%s", BASE_URL, slug),
+		})
 	})
-	return results, nil
+
+	if len(funds) == 0 {
+		return []Fund{}, fmt.Errorf("no funds")
+	}
+
+	fundsCache.Set(BASE_URL, funds)
+	return funds, nil
 }
 
-func GetFeed(fundName string) (string, error) {
-	results, err := GetLatestMessagesByFundName(fundName)
+func GetSections(fundName string) ([]Section, error) {
+	doc, err := fetchFundPage(fundName)
 	if err != nil {
-		return "", err
+		return []Section{}, err
+	}
+	return findSections(doc), nil
+}
+
+func GetLatestMessagesByFundName(fundName string, section string) ([]Message, error) {
+	doc, err := fetchFundPage(fundName)
+	if err != nil {
+		return []Message{}, err
+	}
+
+	var results []Message
+	for _, s := range findSections(doc) {
+		if matchSection(s, section) {
+			results = append(results, parseSection(doc, s)...)
+		}
+	}
+	return results, nil
+}
+
+// FeedPath maps accent-am.ru/funds/<fund> to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "accent-am.ru" || len(parts) < 2 || parts[0] != "funds" {
+		return "", false
+	}
+
+	fundName := utils.StringsAllowlist(parts[1], VALID_FUND_PATTERN)
+	if fundName == "" || fundName != parts[1] {
+		return "", false
 	}
+	return fmt.Sprintf("/accent-am/%s", fundName), true
+}
 
-	if len(results) == 0 {
-		return "", fmt.Errorf("no messages")
+// GetAggregateFeed combines documents of every listed fund into one feed,
+// prefixing each item with its fund name.
+func GetAggregateFeed(section string, paragraphs int) (*utils.Feed, error) {
+	funds, err := GetFunds()
+	if err != nil {
+		return nil, err
 	}
 
-	feed := &feeds.Feed{
-		Title: fmt.Sprintf("Фонд акцент @%s", fundName),
+	resultsByFund := make([][]Message, len(funds))
+	utils.Parallel(len(funds), AGGREGATE_WORKERS, func(i int) {
+		results, err := GetLatestMessagesByFundName(funds[i].Slug, section)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		resultsByFund[i] = results
+	})
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: "Фонды акцент",
 		Link: &feeds.Link{
-			Href: fmt.Sprintf("%s/funds/%s", BASE_URL, fundName),
+			Href: fmt.Sprintf("%s/funds", BASE_URL),
 		},
-		Description: fmt.Sprintf("Сообщения фонда %s", fundName),
-	}
+		Description: "Документы всех фондов акцент",
+	})
 
 	seenSet := mapset.NewSet[string]()
-	for _, entry := range results {
+	for i, fund := range funds {
+		for _, entry := range resultsByFund[i] {
+
+			url := entry.URL
 
-		url := entry.URL
+			if seenSet.Contains(url) {
+				continue
+			}
 
-		if seenSet.Contains(url) {
-			continue
+			seenSet.Add(url)
+			feed.Items = append(feed.Items, &feeds.Item{
+				Title:   fmt.Sprintf("%s: %s", fund.Name, entry.Title),
+				Link:    &feeds.Link{Href: url},
+				Author:  &feeds.Author{Name: fund.Name},
+				Created: entry.Date,
+				Id:      url,
+			})
+			feed.SetCategory(url, entry.Section)
 		}
+	}
 
-		seenSet.Add(url)
-		feed.Items = append(feed.Items, &feeds.Item{
-			Title:   entry.Title,
-			Link:    &feeds.Link{Href: url},
-			Created: entry.Date,
-			Id:      url,
-		})
+	if len(feed.Items) == 0 {
+		return nil, fmt.Errorf("no messages")
+	}
+
+	feed.Sort(func(a, b *feeds.Item) bool {
+		return a.Created.After(b.Created)
+	})
 
+	feed.Enrich = func(items []*feeds.Item) {
+		documents.AttachItems(items, paragraphs)
 	}
 
-	return feed.ToRss()
+	return feed, nil
 }
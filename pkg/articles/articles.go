@@ -0,0 +1,228 @@
+package articles
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+const FULLTEXT_WORKERS = 4
+
+var fullTextCache = utils.NewCache[string](7*24*time.Hour, 2000)
+
+// Site is the listing and article markup of a blog platform. The fields
+// other than Item and Link are looked up inside every Item, an empty Title
+// means the link text is the title.
+type Site struct {
+	BaseUrl   string
+	Item      string
+	Title     string
+	Link      string
+	Author    string
+	Date      string
+	Lead      string
+	Tags      string
+	TagsLabel string
+	Rating    string
+	Comments  string
+	// Body selectors for the full text, tried in order
+	Body []string
+}
+
+type Article struct {
+	Title    string
+	URL      string
+	Author   string
+	Date     time.Time
+	Lead     string
+	Tags     []string
+	Rating   string
+	Comments string
+}
+
+func text(s *goquery.Selection, selector string) string {
+	if selector == "" {
+		return ""
+	}
+	return strings.TrimSpace(s.Find(selector).First().Text())
+}
+
+func parseArticle(site Site, base *url.URL, s *goquery.Selection) (Article, bool) {
+	link := s.Find(site.Link).First()
+	href, ok := link.Attr("href")
+	if !ok {
+		return Article{}, false
+	}
+	ref, err := base.Parse(href)
+	if err != nil {
+		log.Println(err)
+		return Article{}, false
+	}
+
+	article := Article{
+		Title:    strings.TrimSpace(link.Text()),
+		URL:      ref.String(),
+		Author:   text(s, site.Author),
+		Lead:     text(s, site.Lead),
+		Rating:   text(s, site.Rating),
+		Comments: text(s, site.Comments),
+	}
+	if site.Title != "" {
+		article.Title = text(s, site.Title)
+	}
+	if article.Title == "" {
+		article.Title = utils.Truncate(strings.SplitN(article.Lead, "\n", 2)[0], 80)
+	}
+
+	if datetime, ok := s.Find(site.Date).First().Attr("datetime"); ok {
+		date, err := time.Parse(time.RFC3339, datetime)
+		if err != nil {
+			log.Println(err)
+		}
+		article.Date = date
+	}
+
+	s.Find(site.Tags).Each(func(_ int, tag *goquery.Selection) {
+		if name := strings.TrimSpace(tag.Text()); name != "" && !slices.Contains(article.Tags, name) {
+			article.Tags = append(article.Tags, name)
+		}
+	})
+
+	return article, true
+}
+
+func GetLatestArticles(site Site, listUrl string) ([]Article, error) {
+	base, err := url.Parse(site.BaseUrl)
+	if err != nil {
+		return []Article{}, err
+	}
+
+	doc, err := utils.FetchDocument(listUrl)
+	if err != nil {
+		return []Article{}, err
+	}
+
+	var articles []Article
+	doc.Find(site.Item).Each(func(_ int, s *goquery.Selection) {
+		if article, ok := parseArticle(site, base, s); ok {
+			articles = append(articles, article)
+		}
+	})
+	return articles, nil
+}
+
+func GetArticleFullText(site Site, articleUrl string) (string, error) {
+	if content, ok := fullTextCache.Get(articleUrl); ok {
+		return content, nil
+	}
+
+	doc, err := utils.FetchDocument(articleUrl)
+	if err != nil {
+		return "", err
+	}
+
+	var body *goquery.Selection
+	for _, selector := range site.Body {
+		body = doc.Find(selector).First()
+		if body.Length() > 0 {
+			break
+		}
+	}
+	if body == nil || body.Length() == 0 {
+		return "", fmt.Errorf("no article body in %s", articleUrl)
+	}
+
+	raw, err := body.Html()
+	if err != nil {
+		return "", err
+	}
+
+	content, err := utils.SanitizeHtml(raw, articleUrl)
+	if err != nil {
+		return "", err
+	}
+
+	fullTextCache.Set(articleUrl, content)
+	return content, nil
+}
+
+func articleDescription(site Site, article Article) string {
+	var meta []string
+	if article.Rating != "" {
+		meta = append(meta, fmt.Sprintf("Рейтинг: %s", article.Rating))
+	}
+	if article.Comments != "" {
+		meta = append(meta, fmt.Sprintf("Комментарии: %s", article.Comments))
+	}
+	if len(article.Tags) > 0 {
+		meta = append(meta, fmt.Sprintf("%s: %s", site.TagsLabel, strings.Join(article.Tags, ", ")))
+	}
+
+	var b strings.Builder
+	if len(meta) > 0 {
+		fmt.Fprintf(&b, "<p>%s</p>", html.EscapeString(strings.Join(meta, " · ")))
+	}
+	b.WriteString(utils.TextToHtml(utils.Truncate(article.Lead, 500), nil))
+	return b.String()
+}
+
+// GetFeed lists the articles of a page, their full texts are fetched for
+// the items left after filtering.
+func GetFeed(site Site, title, listUrl string) (*utils.Feed, error) {
+	articles, err := GetLatestArticles(site, listUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(articles) == 0 {
+		return nil, fmt.Errorf("no articles")
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: title,
+		Link: &feeds.Link{
+			Href: listUrl,
+		},
+		Description: fmt.Sprintf("Лента RSS %s", title),
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, article := range articles {
+		if seenSet.Contains(article.URL) {
+			continue
+		}
+
+		seenSet.Add(article.URL)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       article.Title,
+			Link:        &feeds.Link{Href: article.URL},
+			Author:      &feeds.Author{Name: article.Author},
+			Description: articleDescription(site, article),
+			Created:     article.Date,
+			Id:          article.URL,
+		})
+		feed.SetCategory(article.URL, strings.Join(article.Tags, ", "))
+	}
+
+	feed.Enrich = func(items []*feeds.Item) {
+		utils.Parallel(len(items), FULLTEXT_WORKERS, func(i int) {
+			content, err := GetArticleFullText(site, items[i].Link.Href)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			items[i].Content = content
+		})
+	}
+
+	return feed, nil
+}
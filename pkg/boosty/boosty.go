@@ -0,0 +1,285 @@
+package boosty
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// https://api.boosty.to/v1/blog/boosty/post/?limit=20
+
+const BOOSTY_API = "https://api.boosty.to/v1"
+const BOOSTY_SITE = "https://boosty.to"
+
+const POSTS_COUNT = "20"
+
+var VALID_BLOG_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	{R16: []unicode.Range16{{'_', '_', 1}}},
+	{R16: []unicode.Range16{{'-', '-', 1}}},
+	{R16: []unicode.Range16{{'.', '.', 1}}},
+}
+
+var PARAMS = []utils.Param{
+	{Name: "blog", Description: "Blog name from boosty.to/name", Example: "boosty", Path: true},
+	{Name: "no_locked", Description: "Hide posts available only to subscribers", Options: []string{"1"}},
+}
+
+// first path segments on boosty.to that are not blogs
+var RESERVED_PATHS = []string{"app", "auth", "about", "faq", "media", "feed", "search", "terms"}
+
+type BoostyBlockJSON struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	URL     string `json:"url"`
+	Preview string `json:"preview"`
+	Title   string `json:"title"`
+}
+
+type BoostyPostJSON struct {
+	ID          string            `json:"id"`
+	Title       string            `json:"title"`
+	PublishTime int               `json:"publishTime"`
+	HasAccess   bool              `json:"hasAccess"`
+	Price       int               `json:"price"`
+	Teaser      []BoostyBlockJSON `json:"teaser"`
+	Data        []BoostyBlockJSON `json:"data"`
+	Tags        []struct {
+		Title string `json:"title"`
+	} `json:"tags"`
+	Count struct {
+		Likes    int `json:"likes"`
+		Comments int `json:"comments"`
+	} `json:"count"`
+	User struct {
+		Name    string `json:"name"`
+		BlogUrl string `json:"blogUrl"`
+	} `json:"user"`
+}
+
+type BoostyPostsJSON struct {
+	Data []BoostyPostJSON `json:"data"`
+}
+
+func GetLatestPostsByBlog(blog string) (BoostyPostsJSON, error) {
+	apiUrl := fmt.Sprintf("%s/blog/%s/post/", BOOSTY_API, blog)
+	params := map[string]string{
+		"limit": POSTS_COUNT,
+	}
+
+	req, err := http.NewRequest("GET", apiUrl, nil)
+	if err != nil {
+		log.Println(err)
+		return BoostyPostsJSON{}, err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+
+	q := req.URL.Query()
+	for key, value := range params {
+		q.Add(key, value)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return BoostyPostsJSON{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return BoostyPostsJSON{}, fmt.Errorf("got status %d for blog %s", resp.StatusCode, blog)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return BoostyPostsJSON{}, err
+	}
+
+	var j BoostyPostsJSON
+	err = json.Unmarshal(body, &j)
+	if err != nil {
+		return BoostyPostsJSON{}, err
+	}
+
+	return j, nil
+}
+
+// FeedPath maps boosty.to/name and boosty.to/name/posts/id to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "boosty.to" || parts[0] == "" || slices.Contains(RESERVED_PATHS, parts[0]) {
+		return "", false
+	}
+
+	blog := utils.StringsAllowlist(parts[0], VALID_BLOG_PATTERN)
+	if blog == "" || blog != parts[0] {
+		return "", false
+	}
+	return fmt.Sprintf("/boosty/%s", blog), true
+}
+
+func postUrl(blog string, post BoostyPostJSON) string {
+	return fmt.Sprintf("%s/%s/posts/%s", BOOSTY_SITE, blog, post.ID)
+}
+
+// blockText reads the text of a block, stored as a JSON array of the text,
+// its style and its formatting ranges.
+func blockText(block BoostyBlockJSON) string {
+	if block.Content == "" {
+		return ""
+	}
+	var content []json.RawMessage
+	if err := json.Unmarshal([]byte(block.Content), &content); err != nil || len(content) == 0 {
+		return block.Content
+	}
+	var text string
+	if err := json.Unmarshal(content[0], &text); err != nil {
+		return ""
+	}
+	return text
+}
+
+func renderBlocks(b *strings.Builder, blocks []BoostyBlockJSON) {
+	var paragraph strings.Builder
+	flush := func() {
+		if paragraph.Len() > 0 {
+			b.WriteString(utils.TextToHtml(paragraph.String(), nil))
+			paragraph.Reset()
+		}
+	}
+
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			// a paragraph is split into several text blocks ending with an empty one
+			text := blockText(block)
+			if text == "" {
+				flush()
+				continue
+			}
+			paragraph.WriteString(text)
+		case "link":
+			flush()
+			text := blockText(block)
+			if text == "" {
+				text = block.URL
+			}
+			fmt.Fprintf(b, `<p><a href="%s">%s</a></p>`, html.EscapeString(block.URL), html.EscapeString(text))
+		case "image":
+			flush()
+			fmt.Fprintf(b, `<p><img src="%s"></p>`, html.EscapeString(block.URL))
+		case "ok_video", "video":
+			flush()
+			if block.Preview != "" {
+				fmt.Fprintf(b, `<p><img src="%s"></p>`, html.EscapeString(block.Preview))
+			}
+		case "audio_file":
+			flush()
+			fmt.Fprintf(b, "<p>Аудио: %s</p>", html.EscapeString(block.Title))
+		}
+	}
+	flush()
+}
+
+func postDescription(post BoostyPostJSON) string {
+	var b strings.Builder
+	if !post.HasAccess {
+		if post.Price > 0 {
+			fmt.Fprintf(&b, "<p>Доступно по подписке от %d ₽</p>", post.Price)
+		} else {
+			b.WriteString("<p>Доступно по подписке</p>")
+		}
+	}
+
+	// locked posts only come with a teaser
+	if post.HasAccess && len(post.Data) > 0 {
+		renderBlocks(&b, post.Data)
+	} else {
+		renderBlocks(&b, post.Teaser)
+	}
+
+	fmt.Fprintf(&b, "<p>Лайки: %d · Комментарии: %d</p>", post.Count.Likes, post.Count.Comments)
+	return b.String()
+}
+
+func postTitle(post BoostyPostJSON) string {
+	title := post.Title
+	if title == "" {
+		title = "Пост"
+	}
+	if !post.HasAccess {
+		return fmt.Sprintf("[PAID] %s", title)
+	}
+	return title
+}
+
+func GetFeed(blog string, noLocked bool) (*utils.Feed, error) {
+	posts, err := GetLatestPostsByBlog(blog)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(posts.Data) == 0 {
+		return nil, fmt.Errorf("no posts")
+	}
+
+	title := posts.Data[0].User.Name
+	if title == "" {
+		title = blog
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: fmt.Sprintf("Boosty @%s", title),
+		Link: &feeds.Link{
+			Href: fmt.Sprintf("%s/%s", BOOSTY_SITE, blog),
+		},
+		Description: fmt.Sprintf("Лента RSS Boosty %s", blog),
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, post := range posts.Data {
+		if noLocked && !post.HasAccess {
+			continue
+		}
+
+		url := postUrl(blog, post)
+
+		if seenSet.Contains(url) {
+			continue
+		}
+
+		seenSet.Add(url)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       postTitle(post),
+			Link:        &feeds.Link{Href: url},
+			Description: postDescription(post),
+			Created:     time.Unix(int64(post.PublishTime), 0),
+			Id:          url,
+		})
+
+		var tags []string
+		for _, tag := range post.Tags {
+			tags = append(tags, tag.Title)
+		}
+		feed.SetCategory(url, strings.Join(tags, ", "))
+	}
+
+	return feed, nil
+}
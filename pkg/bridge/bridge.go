@@ -0,0 +1,100 @@
+package bridge
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/filter"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// Bridge is a feed route together with the function building its feed.
+// FeedPath maps a link to the source site to the bridge route, if the
+// bridge can tell from the link alone. LookupFeedPath does the same for
+// links that need a request to the site, e.g. to find a video's channel.
+type Bridge struct {
+	Name           string
+	Title          string
+	Description    string
+	Path           string // gin route, e.g. /vkvideo/:username
+	Params         []utils.Param
+	GetFeed        func(c *gin.Context) (*utils.Feed, error)
+	FeedPath       func(u *url.URL) (string, bool)
+	LookupFeedPath func(u *url.URL) (string, bool, error)
+}
+
+// Match tells whether urlPath is served by the bridge and extracts its
+// path parameters.
+func (b Bridge) Match(urlPath string) (gin.Params, bool) {
+	pattern := strings.Split(strings.Trim(b.Path, "/"), "/")
+	parts := strings.Split(strings.Trim(urlPath, "/"), "/")
+	if len(pattern) != len(parts) {
+		return nil, false
+	}
+
+	var params gin.Params
+	for i, segment := range pattern {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			if parts[i] == "" {
+				return nil, false
+			}
+			params = append(params, gin.Param{Key: name, Value: parts[i]})
+			continue
+		}
+		if segment != parts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Find returns the bridge serving urlPath, static routes win over ones
+// with parameters just like in the router.
+func Find(bridges []Bridge, urlPath string) (Bridge, gin.Params, bool) {
+	var found Bridge
+	var foundParams gin.Params
+	ok := false
+	for _, b := range bridges {
+		params, matched := b.Match(urlPath)
+		if !matched {
+			continue
+		}
+		if !ok || len(params) < len(foundParams) {
+			found, foundParams, ok = b, params, true
+		}
+	}
+	return found, foundParams, ok
+}
+
+// BuildPath fills the route with path parameters from values and appends
+// every non-empty query parameter the bridge or the filter knows about.
+func (b Bridge) BuildPath(values url.Values) (string, error) {
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(b.Path, "/"), "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			value := strings.TrimSpace(values.Get(name))
+			if value == "" {
+				return "", fmt.Errorf("missing %s", name)
+			}
+			segment = url.PathEscape(value)
+		}
+		segments = append(segments, segment)
+	}
+	path := "/" + strings.Join(segments, "/")
+
+	query := url.Values{}
+	for _, param := range append(b.Params, filter.PARAMS...) {
+		if param.Path {
+			continue
+		}
+		if value := strings.TrimSpace(values.Get(param.Name)); value != "" {
+			query.Set(param.Name, value)
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
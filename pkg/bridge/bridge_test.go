@@ -0,0 +1,71 @@
+package bridge
+
+import (
+	"net/url"
+	"testing"
+)
+
+var testBridges = []Bridge{
+	{Name: "accent-am", Path: "/accent-am/:fund_name"},
+	{Name: "accent-am-all", Path: "/accent-am/all"},
+	{Name: "fund-manager", Path: "/fund-manager/:manager/:fund_name"},
+	{Name: "cbr", Path: "/cbr"},
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		path     string
+		want     string
+		wantOk   bool
+		wantArgs string
+	}{
+		{"/cbr", "cbr", true, ""},
+		{"/cbr/", "cbr", true, ""},
+		{"/accent-am/all", "accent-am-all", true, ""},
+		{"/accent-am/fund", "accent-am", true, "fund_name=fund"},
+		{"/fund-manager/example-am/fund", "fund-manager", true, "fund_name=fund&manager=example-am"},
+		{"/fund-manager/example-am", "", false, ""},
+		{"/accent-am", "", false, ""},
+		{"/unknown", "", false, ""},
+	}
+
+	for _, tt := range tests {
+		b, params, ok := Find(testBridges, tt.path)
+		if ok != tt.wantOk || b.Name != tt.want {
+			t.Errorf("Find(%q) = %q, %v, want %q, %v", tt.path, b.Name, ok, tt.want, tt.wantOk)
+			continue
+		}
+		args := url.Values{}
+		for _, param := range params {
+			args.Set(param.Key, param.Value)
+		}
+		if got := args.Encode(); got != tt.wantArgs {
+			t.Errorf("Find(%q) params = %q, want %q", tt.path, got, tt.wantArgs)
+		}
+	}
+}
+
+func TestBuildPath(t *testing.T) {
+	b := testBridges[2]
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"manager=example-am&fund_name=fund", "/fund-manager/example-am/fund", false},
+		{"manager=example-am&fund_name=a b&limit=5&unknown=1", "/fund-manager/example-am/a%20b?limit=5", false},
+		{"manager=example-am", "", true},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		got, err := b.BuildPath(values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("BuildPath(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("BuildPath(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
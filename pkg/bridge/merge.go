@@ -0,0 +1,160 @@
+package bridge
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/filter"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+const MAX_MERGED_FEEDS = 20
+const DEFAULT_MERGE_WINDOW = 6 * time.Hour
+
+// NormalizeTitle keeps only lowercased letters and digits separated by
+// single spaces, so the same video titled slightly differently still matches.
+func NormalizeTitle(title string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, title)
+	return strings.Join(strings.Fields(mapped), " ")
+}
+
+// FetchFeed builds the feed of a bridge route like "/rutube/123?limit=5"
+// in-process, applying the filter from its own query string.
+func FetchFeed(bridges []Bridge, feedUrl string) (*utils.Feed, error) {
+	u, err := url.Parse(feedUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	b, params, ok := Find(bridges, u.Path)
+	if !ok {
+		return nil, fmt.Errorf("no bridge for %s", u.Path)
+	}
+
+	itemFilter, err := filter.Parse(u.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", u.RequestURI(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := b.GetFeed(&gin.Context{Request: req, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	itemFilter.Apply(feed)
+	return feed, nil
+}
+
+// isDuplicate matches items by normalized title and date, untitled and
+// undated items are never considered the same.
+func isDuplicate(a, b *feeds.Item, window time.Duration) bool {
+	title := NormalizeTitle(a.Title)
+	if title == "" || title != NormalizeTitle(b.Title) {
+		return false
+	}
+	if a.Created.IsZero() || b.Created.IsZero() {
+		return false
+	}
+	return a.Created.Sub(b.Created).Abs() <= window
+}
+
+// Merge combines feeds into one sorted by date. Items of different feeds
+// with the same normalized title published within window of each other are
+// kept only once, preferring the feed listed first.
+func Merge(sources []*utils.Feed, window time.Duration) *utils.Feed {
+	merged := utils.NewFeed(&feeds.Feed{})
+
+	var titles []string
+	for _, source := range sources {
+		titles = append(titles, source.Title)
+
+		// items of the same source are never duplicates of each other
+		previous := merged.Items
+		for _, item := range source.Items {
+			duplicate := false
+			for _, kept := range previous {
+				if isDuplicate(item, kept, window) {
+					duplicate = true
+					break
+				}
+			}
+			if duplicate {
+				continue
+			}
+
+			merged.Items = append(merged.Items, item)
+			if category, ok := source.Categories[item.Id]; ok {
+				merged.SetCategory(item.Id, category)
+			}
+			if duration, ok := source.Durations[item.Id]; ok {
+				merged.SetDuration(item.Id, duration)
+			}
+		}
+	}
+
+	merged.Title = strings.Join(titles, " + ")
+	merged.Description = fmt.Sprintf("Объединённая лента: %s", merged.Title)
+	merged.Link = &feeds.Link{}
+	if len(sources) > 0 && sources[0].Link != nil {
+		merged.Link.Href = sources[0].Link.Href
+	}
+
+	merged.Sort(func(a, b *feeds.Item) bool {
+		return a.Created.After(b.Created)
+	})
+	return merged
+}
+
+// MergeFeeds fetches every route in feedUrls concurrently and merges them.
+// Failing sources are logged and skipped.
+func MergeFeeds(bridges []Bridge, feedUrls []string, window time.Duration) (*utils.Feed, error) {
+	if len(feedUrls) == 0 || len(feedUrls) > MAX_MERGED_FEEDS {
+		return nil, fmt.Errorf("expected 1 to %d feeds, got %d", MAX_MERGED_FEEDS, len(feedUrls))
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*utils.Feed, len(feedUrls))
+	for i, feedUrl := range feedUrls {
+		wg.Add(1)
+		go func(i int, feedUrl string) {
+			defer wg.Done()
+			feed, err := FetchFeed(bridges, feedUrl)
+			if err != nil {
+				log.Printf("merge: %s: %v\n", feedUrl, err)
+				return
+			}
+			results[i] = feed
+		}(i, feedUrl)
+	}
+	wg.Wait()
+
+	var sources []*utils.Feed
+	for _, feed := range results {
+		if feed != nil {
+			sources = append(sources, feed)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no feeds could be fetched")
+	}
+
+	return Merge(sources, window), nil
+}
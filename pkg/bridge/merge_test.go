@@ -0,0 +1,125 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"Выпуск №12: Итоги!", "выпуск 12 итоги"},
+		{"  Hello,   World  ", "hello world"},
+		{"[LIVE] Stream", "live stream"},
+		{"🔥🔥🔥", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.input); got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func testFeed(title string, items ...*feeds.Item) *utils.Feed {
+	feed := utils.NewFeed(&feeds.Feed{Title: title, Link: &feeds.Link{Href: "https://example.ru/" + title}})
+	feed.Items = items
+	return feed
+}
+
+func TestMerge(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	item := func(id, title string, created time.Time) *feeds.Item {
+		return &feeds.Item{Id: id, Title: title, Created: created}
+	}
+
+	tests := []struct {
+		name    string
+		sources []*utils.Feed
+		want    []string
+	}{
+		{
+			"same titles within one source are kept",
+			[]*utils.Feed{testFeed("tg",
+				item("1", "Фото", now),
+				item("2", "Фото", now.Add(-time.Minute)),
+				item("3", "", now.Add(-2*time.Minute)),
+				item("4", "", now.Add(-3*time.Minute)),
+			)},
+			[]string{"1", "2", "3", "4"},
+		},
+		{
+			"duplicate across sources keeps the first source",
+			[]*utils.Feed{
+				testFeed("a", item("a1", "Новый выпуск!", now)),
+				testFeed("b", item("b1", "новый выпуск", now.Add(time.Hour))),
+			},
+			[]string{"a1"},
+		},
+		{
+			"same title outside the window is kept",
+			[]*utils.Feed{
+				testFeed("a", item("a1", "Выпуск", now)),
+				testFeed("b", item("b1", "Выпуск", now.Add(-7*time.Hour))),
+			},
+			[]string{"a1", "b1"},
+		},
+		{
+			"empty titles are never duplicates",
+			[]*utils.Feed{
+				testFeed("a", item("a1", "", now)),
+				testFeed("b", item("b1", "!!!", now)),
+			},
+			[]string{"a1", "b1"},
+		},
+		{
+			"undated items are never duplicates",
+			[]*utils.Feed{
+				testFeed("a", item("a1", "Выпуск", time.Time{})),
+				testFeed("b", item("b1", "Выпуск", now)),
+			},
+			[]string{"b1", "a1"},
+		},
+	}
+
+	for _, tt := range tests {
+		merged := Merge(tt.sources, DEFAULT_MERGE_WINDOW)
+		var got []string
+		for _, item := range merged.Items {
+			got = append(got, item.Id)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMergeKeepsMetadata(t *testing.T) {
+	a := testFeed("a", &feeds.Item{Id: "a1", Title: "Видео"})
+	a.SetCategory("a1", "Новости")
+	a.SetDuration("a1", time.Minute)
+	b := testFeed("b", &feeds.Item{Id: "b1", Title: "Пост"})
+
+	merged := Merge([]*utils.Feed{a, b}, DEFAULT_MERGE_WINDOW)
+	if merged.Title != "a + b" {
+		t.Errorf("Title = %q, want %q", merged.Title, "a + b")
+	}
+	if merged.Link.Href != "https://example.ru/a" {
+		t.Errorf("Link = %q, want the first source", merged.Link.Href)
+	}
+	if merged.Categories["a1"] != "Новости" || merged.Durations["a1"] != time.Minute {
+		t.Errorf("category and duration of a1 were lost")
+	}
+}
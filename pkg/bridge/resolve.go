@@ -0,0 +1,91 @@
+package bridge
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseSourceUrl accepts links with or without a scheme and strips "www."
+// and "m." so bridges only have to compare bare hostnames.
+func ParseSourceUrl(rawUrl string) (*url.URL, error) {
+	rawUrl = strings.TrimSpace(rawUrl)
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "https://" + rawUrl
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("no host in %s", rawUrl)
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	u.Host = strings.TrimPrefix(u.Host, "www.")
+	u.Host = strings.TrimPrefix(u.Host, "m.")
+	return u, nil
+}
+
+// an OPML import may list many links, only the first ones are resolved and
+// only a few of them may ask the sites
+const (
+	MAX_IMPORTED_URLS  = 500
+	MAX_IMPORT_LOOKUPS = 20
+)
+
+func matchFeedPath(bridges []Bridge, u *url.URL) (string, bool) {
+	for _, b := range bridges {
+		if b.FeedPath == nil {
+			continue
+		}
+		if path, ok := b.FeedPath(u); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// ResolveOffline finds the bridge route for a link recognised as is,
+// without requests to the source site.
+func ResolveOffline(bridges []Bridge, rawUrl string) (string, error) {
+	u, err := ParseSourceUrl(rawUrl)
+	if err != nil {
+		return "", err
+	}
+
+	if path, ok := matchFeedPath(bridges, u); ok {
+		return path, nil
+	}
+	return "", fmt.Errorf("no bridge for %s", rawUrl)
+}
+
+// Resolve finds the bridge route for a link to the source site, trying
+// links recognised as is before asking the sites.
+func Resolve(bridges []Bridge, rawUrl string) (string, error) {
+	u, err := ParseSourceUrl(rawUrl)
+	if err != nil {
+		return "", err
+	}
+
+	if path, ok := matchFeedPath(bridges, u); ok {
+		return path, nil
+	}
+
+	for _, b := range bridges {
+		if b.LookupFeedPath == nil {
+			continue
+		}
+		path, ok, err := b.LookupFeedPath(u)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	return "", fmt.Errorf("no bridge for %s", rawUrl)
+}
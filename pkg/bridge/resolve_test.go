@@ -0,0 +1,115 @@
+package bridge_test
+
+import (
+	"testing"
+
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/accentAm"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/boosty"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/bridge"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/cbr"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/dzen"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/edisclosure"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/habr"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/okru"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/rutube"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/smotrim"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/telegram"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/vcru"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/vkvideo"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/vkwall"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/yandexmusic"
+)
+
+// in the order of the registry in cmd/main.go
+var testBridges = []bridge.Bridge{
+	{FeedPath: vkvideo.FeedPath},
+	{FeedPath: vkwall.FeedPath},
+	{FeedPath: dzen.FeedPath},
+	{FeedPath: rutube.FeedPath},
+	{FeedPath: smotrim.FeedPath},
+	{FeedPath: okru.FeedPath},
+	{FeedPath: yandexmusic.FeedPath},
+	{FeedPath: telegram.FeedPath},
+	{FeedPath: boosty.FeedPath},
+	{FeedPath: habr.FeedPath},
+	{FeedPath: vcru.FeedPath},
+	{FeedPath: edisclosure.FeedPath},
+	{FeedPath: cbr.FeedPath},
+	{FeedPath: accentAm.FeedPath},
+}
+
+func TestResolveOffline(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://vkvideo.ru/@durov", "/vkvideo/durov"},
+		{"https://vk.com/video/@durov", "/vkvideo/durov"},
+		{"https://vk.com/durov", "/vkwall/durov"},
+		{"https://m.vk.com/wall-1_123", "/vkwall/club1"},
+		{"vk.com/wall1_123", "/vkwall/id1"},
+		{"https://vk.com/photo1_2", ""},
+		{"https://vk.com/feed", ""},
+		{"https://dzen.ru/konstantinromanov", "/dzen/konstantinromanov"},
+		{"https://dzen.ru/a/abc", ""},
+		{"https://rutube.ru/channel/23704195/", "/rutube/23704195"},
+		{"https://rutube.ru/video/abc/", ""},
+		{"https://smotrim.ru/brand/60941", "/smotrim/60941"},
+		{"https://ok.ru/video/c123456", "/okru/123456"},
+		{"https://ok.ru/video/123456", ""},
+		{"https://music.yandex.ru/album/123456", "/yandex-music/123456"},
+		{"https://t.me/s/durov", "/telegram/durov"},
+		{"https://t.me/joinchat", ""},
+		{"https://boosty.to/example/posts/1", "/boosty/example"},
+		{"https://boosty.to/app/settings", ""},
+		{"https://habr.com/ru/users/varanio/publications/articles/", "/habr/user/varanio"},
+		{"https://habr.com/hubs/go/", "/habr/hub/go"},
+		{"https://habr.com/ru/articles/123/", ""},
+		{"https://vc.ru/u/1389185-ivan-petrov", "/vcru/u/1389185-ivan-petrov"},
+		{"https://vc.ru/marketing/123-slug", "/vcru/marketing"},
+		{"https://vc.ru/tag/ai", ""},
+		{"https://www.e-disclosure.ru/portal/company.aspx?id=2347", "/e-disclosure/2347"},
+		{"https://e-disclosure.ru/Portal/Events.aspx?id=2347", "/e-disclosure/2347"},
+		{"https://e-disclosure.ru/portal/company.aspx?id=x", ""},
+		{"https://cbr.ru/press/event/", "/cbr"},
+		{"https://accent-am.ru/funds/aktsent-5-fond-nedvizhimosti", "/accent-am/aktsent-5-fond-nedvizhimosti"},
+		{"https://example.com/", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := bridge.ResolveOffline(testBridges, tt.url)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("ResolveOffline(%q) = %q, want an error", tt.url, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveOffline(%q) = %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSourceUrl(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantHost string
+		wantErr  bool
+	}{
+		{"https://www.Habr.com/ru/", "habr.com", false},
+		{"m.vk.com/durov", "vk.com", false},
+		{"  t.me/durov  ", "t.me", false},
+		{"https:///path", "", true},
+	}
+
+	for _, tt := range tests {
+		u, err := bridge.ParseSourceUrl(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSourceUrl(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err == nil && u.Host != tt.wantHost {
+			t.Errorf("ParseSourceUrl(%q) host = %q, want %q", tt.input, u.Host, tt.wantHost)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package cbr
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+	"golang.org/x/net/html/charset"
+)
+
+// https://www.cbr.ru/rss/RssPress
+// https://www.cbr.ru/scripts/XML_daily.asp?date_req=19/10/2026
+
+const CBR_SITE = "https://www.cbr.ru"
+
+const SOURCE_NAME = "cbr"
+const SOURCE_TIMEZONE = utils.MOSCOW_TIMEZONE
+
+const (
+	CATEGORY_PRESS    = "Пресс-релизы"
+	CATEGORY_KEY_RATE = "Ключевая ставка"
+	CATEGORY_RATES    = "Курсы валют"
+)
+
+// press releases about key rate decisions mention it in the title
+var KEY_RATE_KEYWORDS = []string{"ключевую ставку", "ключевой ставки", "ключевая ставка"}
+
+var VALID_CURRENCIES_PATTERN = []*unicode.RangeTable{
+	unicode.Latin,
+	{R16: []unicode.Range16{{',', ',', 1}}},
+}
+
+var PARAMS = []utils.Param{
+	{Name: "rates", Description: "Comma separated currency codes for a daily item with official exchange rates", Example: "USD,EUR,CNY"},
+	{Name: "key_rate", Description: "Keep only key rate decisions", Options: []string{"1"}},
+}
+
+var ratesCache = utils.NewCache[Rates](time.Hour, 30)
+
+type PressItemXML struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Guid        string `xml:"guid"`
+}
+
+type PressXML struct {
+	Channel struct {
+		Items []PressItemXML `xml:"item"`
+	} `xml:"channel"`
+}
+
+type ValuteXML struct {
+	CharCode string `xml:"CharCode"`
+	Nominal  string `xml:"Nominal"`
+	Name     string `xml:"Name"`
+	Value    string `xml:"Value"`
+}
+
+type ValCursXML struct {
+	Date    string      `xml:"Date,attr"`
+	Valutes []ValuteXML `xml:"Valute"`
+}
+
+type Rate struct {
+	Code    string
+	Name    string
+	Nominal int
+	Value   float64
+}
+
+type Rates struct {
+	Date  time.Time
+	Rates map[string]Rate
+}
+
+func getXML(url string, v any) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %d for %s", resp.StatusCode, url)
+	}
+
+	// the rates are served in windows-1251
+	decoder := xml.NewDecoder(resp.Body)
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder.Decode(v)
+}
+
+func GetPressReleases() ([]PressItemXML, error) {
+	var j PressXML
+	err := getXML(fmt.Sprintf("%s/rss/RssPress", CBR_SITE), &j)
+	if err != nil {
+		return []PressItemXML{}, err
+	}
+	return j.Channel.Items, nil
+}
+
+func parseRateValue(s string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), 64)
+}
+
+// GetRates reads the official rates set on a date, or on the last working
+// day before it.
+func GetRates(date time.Time) (Rates, error) {
+	key := date.Format("02/01/2006")
+	if rates, ok := ratesCache.Get(key); ok {
+		return rates, nil
+	}
+
+	var j ValCursXML
+	err := getXML(fmt.Sprintf("%s/scripts/XML_daily.asp?date_req=%s", CBR_SITE, key), &j)
+	if err != nil {
+		return Rates{}, err
+	}
+
+	loc := utils.SourceLocation(SOURCE_NAME, SOURCE_TIMEZONE)
+	ratesDate, err := time.ParseInLocation("02.01.2006", j.Date, loc)
+	if err != nil {
+		return Rates{}, err
+	}
+
+	rates := Rates{Date: ratesDate, Rates: map[string]Rate{}}
+	for _, valute := range j.Valutes {
+		value, err := parseRateValue(valute.Value)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		nominal, err := strconv.Atoi(strings.TrimSpace(valute.Nominal))
+		if err != nil {
+			nominal = 1
+		}
+		rates.Rates[valute.CharCode] = Rate{
+			Code:    valute.CharCode,
+			Name:    valute.Name,
+			Nominal: nominal,
+			Value:   value,
+		}
+	}
+
+	ratesCache.Set(key, rates)
+	return rates, nil
+}
+
+// ParseCurrencies splits a comma separated rates= value into currency codes.
+func ParseCurrencies(s string) []string {
+	var codes []string
+	for _, code := range strings.Split(s, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" && !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// FeedPath maps links to cbr.ru press releases to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	if u.Host != "cbr.ru" || !strings.HasPrefix(u.Path, "/press/") {
+		return "", false
+	}
+	return "/cbr", true
+}
+
+func IsKeyRateDecision(title string) bool {
+	title = strings.ToLower(title)
+	for _, keyword := range KEY_RATE_KEYWORDS {
+		if strings.Contains(title, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePubDate reads RSS dates with a numeric offset or a zone name, names
+// like MSK are looked up in the source timezone.
+func parsePubDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	date, err := time.Parse(time.RFC1123Z, s)
+	if err == nil {
+		return date, nil
+	}
+	return time.ParseInLocation(time.RFC1123, s, utils.SourceLocation(SOURCE_NAME, SOURCE_TIMEZONE))
+}
+
+// ratesItem summarizes the latest official rates of the chosen currencies
+// and their change since the previous setting.
+func ratesItem(currencies []string) (*feeds.Item, error) {
+	loc := utils.SourceLocation(SOURCE_NAME, SOURCE_TIMEZONE)
+	// rates for tomorrow are published in the afternoon
+	latest, err := GetRates(time.Now().In(loc).AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	previous, err := GetRates(latest.Date.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+
+	day := latest.Date.Format("02.01.2006")
+	var b strings.Builder
+	var summary []string
+	b.WriteString("<table><tr><th>Валюта</th><th>Курс</th><th>Изменение</th></tr>")
+	for _, code := range currencies {
+		rate, ok := latest.Rates[code]
+		if !ok {
+			log.Printf("unknown currency %s", code)
+			continue
+		}
+
+		change := "—"
+		if before, ok := previous.Rates[code]; ok && before.Nominal == rate.Nominal {
+			change = fmt.Sprintf("%+.4f", rate.Value-before.Value)
+		}
+
+		name := rate.Name
+		if rate.Nominal != 1 {
+			name = fmt.Sprintf("%d %s", rate.Nominal, name)
+		}
+		fmt.Fprintf(&b, "<tr><td>%s (%s)</td><td>%.4f</td><td>%s</td></tr>",
+			html.EscapeString(name), html.EscapeString(code), rate.Value, change)
+		summary = append(summary, fmt.Sprintf("%s %.2f", code, rate.Value))
+	}
+	b.WriteString("</table>")
+
+	if len(summary) == 0 {
+		return nil, fmt.Errorf("no rates for %s", strings.Join(currencies, ","))
+	}
+
+	url := fmt.Sprintf("%s/currency_base/daily/?UniDbQuery.Posted=True&UniDbQuery.To=%s", CBR_SITE, day)
+	return &feeds.Item{
+		Title:       fmt.Sprintf("Курсы ЦБ на %s: %s", day, strings.Join(summary, ", ")),
+		Link:        &feeds.Link{Href: url},
+		Description: b.String(),
+		Created:     latest.Date,
+		Id:          url,
+	}, nil
+}
+
+func GetFeed(currencies []string, keyRateOnly bool) (*utils.Feed, error) {
+	releases, err := GetPressReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: "Банк России",
+		Link: &feeds.Link{
+			Href: fmt.Sprintf("%s/press/event/", CBR_SITE),
+		},
+		Description: "Пресс-релизы и решения по ключевой ставке Банка России",
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, entry := range releases {
+		keyRate := IsKeyRateDecision(entry.Title)
+		if keyRateOnly && !keyRate {
+			continue
+		}
+
+		url := strings.TrimSpace(entry.Link)
+
+		if url == "" || seenSet.Contains(url) {
+			continue
+		}
+
+		created, err := parsePubDate(entry.PubDate)
+		if err != nil {
+			log.Println(err)
+		}
+
+		seenSet.Add(url)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       strings.TrimSpace(entry.Title),
+			Link:        &feeds.Link{Href: url},
+			Description: entry.Description,
+			Created:     created,
+			Id:          url,
+		})
+		if keyRate {
+			feed.SetCategory(url, CATEGORY_KEY_RATE)
+		} else {
+			feed.SetCategory(url, CATEGORY_PRESS)
+		}
+	}
+
+	if len(currencies) > 0 {
+		item, err := ratesItem(currencies)
+		if err != nil {
+			log.Println(err)
+		} else {
+			feed.Items = append([]*feeds.Item{item}, feed.Items...)
+			feed.SetCategory(item.Id, CATEGORY_RATES)
+		}
+	}
+
+	if len(feed.Items) == 0 {
+		return nil, fmt.Errorf("no press releases")
+	}
+
+	return feed, nil
+}
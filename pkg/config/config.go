@@ -0,0 +1,53 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/fundmanager"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/jsonapi"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/scraper"
+)
+
+// CONFIG_PATH_ENV points to the JSON config, a missing file means an empty
+// config.
+const CONFIG_PATH_ENV = "CONFIG_PATH"
+const DEFAULT_CONFIG_PATH = "config.json"
+
+// FeedConfig is a subscription known to the bridge, Path is a bridge route
+// like /rutube/12345?limit=10.
+type FeedConfig struct {
+	Title string `json:"title"`
+	Path  string `json:"path"`
+}
+
+type Config struct {
+	Feeds        []FeedConfig                 `json:"feeds"`
+	FundManagers []fundmanager.SelectorConfig `json:"fund_managers"`
+	Scrapers     []scraper.Site               `json:"scrapers"`
+	JsonApis     []jsonapi.Api                `json:"json_apis"`
+}
+
+func Load() (Config, error) {
+	path := os.Getenv(CONFIG_PATH_ENV)
+	if path == "" {
+		path = DEFAULT_CONFIG_PATH
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
@@ -0,0 +1,256 @@
+package documents
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+	"github.com/ledongthuc/pdf"
+)
+
+const MAX_ATTACHMENT_SIZE = 32 << 20
+const MAX_PARAGRAPH_LENGTH = 1000
+const DEFAULT_PARAGRAPHS = 3
+const ATTACHMENT_WORKERS = 4
+
+// links with these extensions are treated as documents by IsDocumentUrl
+var DOCUMENT_EXTENSIONS = []string{".pdf", ".doc", ".docx", ".xls", ".xlsx", ".rtf", ".zip"}
+
+// only the newest items get their documents downloaded
+const MAX_ATTACHED_ITEMS = 20
+
+type Attachment struct {
+	Size       int
+	Type       string
+	Paragraphs []string
+}
+
+// documents rarely change once published, so keep them for a long time
+var attachmentCache = utils.NewCache[Attachment](30*24*time.Hour, 5000)
+
+// missing and oversize documents are not retried on every poll, network
+// errors and server failures are
+var attachmentErrorCache = utils.NewCache[error](6*time.Hour, 5000)
+
+// permanentError marks a download failure that retrying will not fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// splitParagraphs joins PDF text rows into paragraphs, a row ending with
+// sentence punctuation closes the current paragraph.
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, " "))
+			current = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+		if strings.HasSuffix(line, ".") || strings.HasSuffix(line, ":") || strings.HasSuffix(line, ";") {
+			flush()
+		}
+	}
+	flush()
+	return paragraphs
+}
+
+func extractPdfText(body []byte) (text string, err error) {
+	// the PDF reader panics on some malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read pdf: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := reader.GetPlainText()
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := io.ReadAll(plain)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// IsDocumentUrl tells whether a link points to a document file by its
+// extension.
+func IsDocumentUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(DOCUMENT_EXTENSIONS, strings.ToLower(path.Ext(u.Path)))
+}
+
+func GetAttachment(url string) (Attachment, error) {
+	if attachment, ok := attachmentCache.Get(url); ok {
+		return attachment, nil
+	}
+	if err, ok := attachmentErrorCache.Get(url); ok {
+		return Attachment{}, err
+	}
+
+	attachment, err := fetchAttachment(url)
+	if err != nil {
+		if errors.As(err, &permanentError{}) {
+			attachmentErrorCache.Set(url, err)
+		}
+		return Attachment{}, err
+	}
+
+	attachmentCache.Set(url, attachment)
+	return attachment, nil
+}
+
+func fetchAttachment(url string) (Attachment, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		log.Println(err)
+		return Attachment{}, err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return Attachment{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("got status %d for %s", resp.StatusCode, url)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return Attachment{}, permanentError{err}
+		}
+		return Attachment{}, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MAX_ATTACHMENT_SIZE+1))
+	if err != nil {
+		log.Println(err)
+		return Attachment{}, err
+	}
+	if len(body) > MAX_ATTACHMENT_SIZE {
+		return Attachment{}, permanentError{fmt.Errorf("attachment %s is too large", url)}
+	}
+
+	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || contentType == "application/octet-stream" {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+
+	attachment := Attachment{
+		Size: len(body),
+		Type: contentType,
+	}
+
+	if contentType == "application/pdf" {
+		text, err := extractPdfText(body)
+		if err != nil {
+			log.Println(err)
+		}
+		for _, paragraph := range splitParagraphs(text) {
+			attachment.Paragraphs = append(attachment.Paragraphs, utils.Truncate(paragraph, MAX_PARAGRAPH_LENGTH))
+		}
+	}
+
+	return attachment, nil
+}
+
+func formatSize(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f МБ", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%d КБ", size/(1<<10))
+	}
+	return fmt.Sprintf("%d Б", size)
+}
+
+func formatType(contentType string) string {
+	if sub, ok := strings.CutPrefix(contentType, "application/"); ok {
+		return strings.ToUpper(sub)
+	}
+	return contentType
+}
+
+// attachItem fills in the description and enclosure of an item from the
+// document it links to, with the first paragraphs of its text.
+func attachItem(item *feeds.Item, paragraphs int) {
+	attachment, err := GetAttachment(item.Link.Href)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<p>%s, %s</p>", html.EscapeString(formatType(attachment.Type)), formatSize(attachment.Size))
+	for i, paragraph := range attachment.Paragraphs {
+		if i >= paragraphs {
+			break
+		}
+		fmt.Fprintf(&b, "<p>%s</p>", html.EscapeString(paragraph))
+	}
+
+	item.Description = b.String()
+	item.Enclosure = &feeds.Enclosure{
+		Url:    item.Link.Href,
+		Length: strconv.Itoa(attachment.Size),
+		Type:   attachment.Type,
+	}
+}
+
+// AttachItems fills in the first items from their documents, a few at a
+// time. paragraphs=0 leaves the items as they are.
+func AttachItems(items []*feeds.Item, paragraphs int) {
+	if paragraphs <= 0 {
+		return
+	}
+	if len(items) > MAX_ATTACHED_ITEMS {
+		items = items[:MAX_ATTACHED_ITEMS]
+	}
+
+	utils.Parallel(len(items), ATTACHMENT_WORKERS, func(i int) {
+		attachItem(items[i], paragraphs)
+	})
+}
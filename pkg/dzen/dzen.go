@@ -3,13 +3,18 @@ package dzen
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 	"unicode"
 
+	"github.com/PuerkitoBio/goquery"
 	mapset "github.com/deckarep/golang-set/v2"
 
 	"github.com/gorilla/feeds"
@@ -20,14 +25,44 @@ import (
 
 const DZEN_API = "https://dzen.ru/api/v3"
 
-type DzenVideosJSON struct {
-	Items []struct {
-		Title                 string `json:"title"`
-		Text                  string `json:"text"`
-		ExtLink               string `json:"ext_link"`
-		PublicationDateString string `json:"publication_date"`
-		PublicationDate       int
-	} `json:"items"`
+type DzenItemJSON struct {
+	Type                  string `json:"type"`
+	Title                 string `json:"title"`
+	Text                  string `json:"text"`
+	ExtLink               string `json:"ext_link"`
+	Link                  string `json:"link"`
+	Image                 string `json:"image"`
+	PublicationDateString string `json:"publication_date"`
+	PublicationDate       int
+}
+
+type DzenItemsJSON struct {
+	Items []DzenItemJSON `json:"items"`
+}
+
+const (
+	ITEM_TYPE_ARTICLE = "article"
+	ITEM_TYPE_POST    = "post"
+	ITEM_TYPE_SHORT   = "short"
+	ITEM_TYPE_VIDEO   = "video"
+)
+
+var ITEM_TYPES = []string{ITEM_TYPE_ARTICLE, ITEM_TYPE_POST, ITEM_TYPE_SHORT, ITEM_TYPE_VIDEO}
+
+// tried in order, the first one found on the page wins
+var ARTICLE_BODY_SELECTORS = []string{
+	`[itemprop="articleBody"]`,
+	".article-render",
+	"article",
+}
+
+const FULLTEXT_WORKERS = 4
+
+var fullTextCache = utils.NewCache[string](7*24*time.Hour, 2000)
+
+var VALID_TYPE_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	{R16: []unicode.Range16{{',', ',', 1}}},
 }
 
 var VALID_USERNAME_PATTERN = []*unicode.RangeTable{
@@ -38,7 +73,13 @@ var VALID_USERNAME_PATTERN = []*unicode.RangeTable{
 	{R16: []unicode.Range16{{'.', '.', 1}}},
 }
 
-func GetLatestVideosByUsername(username string) (DzenVideosJSON, error) {
+var PARAMS = []utils.Param{
+	{Name: "username", Description: "Channel name from dzen.ru/name", Example: "konstantinromanov", Path: true},
+	{Name: "type", Description: "Comma separated item types to keep: article, post, short, video", Example: "article,post"},
+	{Name: "fulltext", Description: "Fetch the full text of articles", Options: []string{"1"}},
+}
+
+func GetLatestItemsByUsername(username string) (DzenItemsJSON, error) {
 	apiUrl := fmt.Sprintf("%s/launcher/more", DZEN_API)
 	params := map[string]string{
 		"channel_name": username,
@@ -47,7 +88,7 @@ func GetLatestVideosByUsername(username string) (DzenVideosJSON, error) {
 	req, err := http.NewRequest("GET", apiUrl, nil)
 	if err != nil {
 		log.Println(err)
-		return DzenVideosJSON{}, err
+		return DzenItemsJSON{}, err
 	}
 
 	req.Header.Set("User-Agent", utils.USER_AGENT)
@@ -62,45 +103,216 @@ func GetLatestVideosByUsername(username string) (DzenVideosJSON, error) {
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Println(err)
-		return DzenVideosJSON{}, err
+		return DzenItemsJSON{}, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Println(err)
-		return DzenVideosJSON{}, err
+		return DzenItemsJSON{}, err
 	}
 
-	var j DzenVideosJSON
+	var j DzenItemsJSON
 	err = json.Unmarshal(body, &j)
 	if err != nil {
-		return DzenVideosJSON{}, err
+		return DzenItemsJSON{}, err
 	}
 
 	return j, nil
 }
 
-func GetFeed(username string, skipBefore int) (string, error) {
-	videos, err := GetLatestVideosByUsername(username)
+// GetItemType detects what kind of publication an item is, first from the
+// API type and then from the shape of its link.
+func GetItemType(item DzenItemJSON) string {
+	switch item.Type {
+	case "article":
+		return ITEM_TYPE_ARTICLE
+	case "brief", "post":
+		return ITEM_TYPE_POST
+	case "short_video", "shorts":
+		return ITEM_TYPE_SHORT
+	case "gif", "video", "live":
+		return ITEM_TYPE_VIDEO
+	}
+
+	link := itemLink(item)
+	switch {
+	case strings.Contains(link, "/shorts/"):
+		return ITEM_TYPE_SHORT
+	case strings.Contains(link, "/video/"):
+		return ITEM_TYPE_VIDEO
+	case strings.Contains(link, "/b/"):
+		return ITEM_TYPE_POST
+	}
+	return ITEM_TYPE_ARTICLE
+}
+
+// ParseTypes turns a comma separated type= value into a set of item types,
+// an empty value allows every type.
+func ParseTypes(s string) (mapset.Set[string], error) {
+	types := mapset.NewSet[string]()
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !slices.Contains(ITEM_TYPES, t) {
+			return nil, fmt.Errorf("unknown item type %s", t)
+		}
+		types.Add(t)
+	}
+	if types.Cardinality() == 0 {
+		types.Append(ITEM_TYPES...)
+	}
+	return types, nil
+}
+
+func itemLink(item DzenItemJSON) string {
+	if item.ExtLink != "" {
+		return item.ExtLink
+	}
+	return item.Link
+}
+
+func itemTitle(item DzenItemJSON, itemType string) string {
+	if item.Title == "" && itemType == ITEM_TYPE_POST {
+		return utils.Truncate(strings.SplitN(item.Text, "\n", 2)[0], 80)
+	}
+	return item.Title
+}
+
+func itemDescription(item DzenItemJSON, itemType string) string {
+	var image string
+	if item.Image != "" {
+		image = fmt.Sprintf(`<p><img src="%s"></p>`, html.EscapeString(item.Image))
+	}
+
+	switch itemType {
+	case ITEM_TYPE_ARTICLE:
+		return image + utils.TextToHtml(utils.Truncate(item.Text, 500), nil)
+	case ITEM_TYPE_POST:
+		return utils.TextToHtml(item.Text, nil) + image
+	default:
+		return image + utils.TextToHtml(item.Text, nil)
+	}
+}
+
+func GetArticleFullText(articleUrl string) (string, error) {
+	if content, ok := fullTextCache.Get(articleUrl); ok {
+		return content, nil
+	}
+
+	doc, err := utils.FetchDocument(articleUrl)
+	if err != nil {
+		return "", err
+	}
+
+	var body *goquery.Selection
+	for _, selector := range ARTICLE_BODY_SELECTORS {
+		body = doc.Find(selector).First()
+		if body.Length() > 0 {
+			break
+		}
+	}
+	if body.Length() == 0 {
+		return "", fmt.Errorf("no article body in %s", articleUrl)
+	}
+
+	raw, err := body.Html()
 	if err != nil {
 		return "", err
 	}
 
-	if len(videos.Items) == 0 {
-		return "", fmt.Errorf("no videos")
+	content, err := utils.SanitizeHtml(raw, articleUrl)
+	if err != nil {
+		return "", err
+	}
+
+	fullTextCache.Set(articleUrl, content)
+	return content, nil
+}
+
+// first path segments on dzen.ru that are not channel names
+var RESERVED_PATHS = []string{"a", "b", "video", "shorts", "news", "articles", "topic", "suite", "id", "embed", "feed", "api"}
+
+// FeedPath maps dzen.ru/<name> to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "dzen.ru" || parts[0] == "" || slices.Contains(RESERVED_PATHS, parts[0]) {
+		return "", false
+	}
+
+	username := utils.StringsAllowlist(parts[0], VALID_USERNAME_PATTERN)
+	if username == "" || username != parts[0] {
+		return "", false
 	}
+	return fmt.Sprintf("/dzen/%s", username), true
+}
 
-	feed := &feeds.Feed{
-		Title: fmt.Sprintf("Dzen Video @%s", username),
+// page links that may point to the channel, publication pages carry
+// their own address in the canonical link, so the author link goes first
+var CHANNEL_LINK_SELECTORS = []string{
+	"link[rel=author]",
+	"meta[property='article:author']",
+	"link[rel=canonical]",
+	"meta[property='og:url']",
+}
+
+// LookupFeedPath finds the channel of an article, post or video link by
+// reading the links of the publication page.
+func LookupFeedPath(u *url.URL) (string, bool, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "dzen.ru" || len(parts) < 2 || !slices.Contains(RESERVED_PATHS, parts[0]) {
+		return "", false, nil
+	}
+
+	doc, err := utils.FetchDocument(u.String())
+	if err != nil {
+		return "", true, err
+	}
+
+	for _, selector := range CHANNEL_LINK_SELECTORS {
+		s := doc.Find(selector).First()
+		href, ok := s.Attr("href")
+		if !ok {
+			href, ok = s.Attr("content")
+		}
+		if !ok {
+			continue
+		}
+		ref, err := u.Parse(href)
+		if err != nil {
+			continue
+		}
+		if path, ok := FeedPath(ref); ok {
+			return path, true, nil
+		}
+	}
+	return "", true, fmt.Errorf("no channel found on %s", u.String())
+}
+
+func GetFeed(username string, types mapset.Set[string], fullText bool) (*utils.Feed, error) {
+	items, err := GetLatestItemsByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items.Items) == 0 {
+		return nil, fmt.Errorf("no items")
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: fmt.Sprintf("Dzen @%s", username),
 		Link: &feeds.Link{
 			Href: fmt.Sprintf("https://dzen.ru/%s", username),
 		},
 		Description: fmt.Sprintf("Лента RSS Dzen %s", username),
-	}
+	})
 
+	articleSet := mapset.NewSet[string]()
 	seenSet := mapset.NewSet[string]()
-	for _, entry := range videos.Items {
+	for _, entry := range items.Items {
 
 		publicationDate, err := strconv.Atoi(entry.PublicationDateString)
 		if err != nil {
@@ -108,24 +320,50 @@ func GetFeed(username string, skipBefore int) (string, error) {
 			continue
 		}
 
-		if skipBefore > publicationDate {
+		itemType := GetItemType(entry)
+		if !types.Contains(itemType) {
 			continue
 		}
-		videoUrl := entry.ExtLink
 
-		if seenSet.Contains(videoUrl) {
+		itemUrl := itemLink(entry)
+
+		if seenSet.Contains(itemUrl) {
 			continue
 		}
 
-		seenSet.Add(videoUrl)
-		feed.Items = append(feed.Items, &feeds.Item{
-			Title:   entry.Title,
-			Link:    &feeds.Link{Href: videoUrl},
-			Created: time.Unix(int64(publicationDate), 0),
-			Id:      videoUrl,
-		})
+		seenSet.Add(itemUrl)
+		item := &feeds.Item{
+			Title:       itemTitle(entry, itemType),
+			Link:        &feeds.Link{Href: itemUrl},
+			Description: itemDescription(entry, itemType),
+			Created:     time.Unix(int64(publicationDate), 0),
+			Id:          itemUrl,
+		}
+		feed.Items = append(feed.Items, item)
 
+		if itemType == ITEM_TYPE_ARTICLE {
+			articleSet.Add(item.Id)
+		}
+	}
+
+	if fullText {
+		feed.Enrich = func(items []*feeds.Item) {
+			var articles []*feeds.Item
+			for _, item := range items {
+				if articleSet.Contains(item.Id) {
+					articles = append(articles, item)
+				}
+			}
+			utils.Parallel(len(articles), FULLTEXT_WORKERS, func(i int) {
+				content, err := GetArticleFullText(articles[i].Link.Href)
+				if err != nil {
+					log.Println(err)
+					return
+				}
+				articles[i].Content = content
+			})
+		}
 	}
 
-	return feed.ToRss()
+	return feed, nil
 }
@@ -0,0 +1,373 @@
+package edisclosure
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// https://www.e-disclosure.ru/portal/events.aspx?id=934
+// https://www.e-disclosure.ru/portal/files.aspx?id=934&type=2
+
+const BASE_URL = "https://www.e-disclosure.ru/portal"
+
+const SOURCE_NAME = "e-disclosure"
+const SOURCE_TIMEZONE = utils.MOSCOW_TIMEZONE
+
+const (
+	KIND_EVENTS    = "events"
+	KIND_DOCUMENTS = "documents"
+)
+
+var KINDS = []string{KIND_EVENTS, KIND_DOCUMENTS}
+
+const DOCUMENT_WORKERS = 4
+
+// files.aspx type= values and the names of their tabs
+var DOCUMENT_TYPES = []struct {
+	ID   int
+	Name string
+}{
+	{2, "Годовая отчетность"},
+	{3, "Бухгалтерская отчетность"},
+	{4, "Консолидированная отчетность"},
+	{5, "Отчет эмитента"},
+	{6, "Аффилированные лица"},
+	{8, "Устав и внутренние документы"},
+}
+
+// event categories by the words their titles contain, the first match wins
+var EVENT_CATEGORIES = []struct {
+	Category string
+	Keywords []string
+}{
+	{"Дивиденды", []string{"дивиденд", "начисленных доходах", "выплаченных доходах"}},
+	{"Общее собрание", []string{"общего собрания", "общем собрании", "собрании владельцев"}},
+	{"Совет директоров", []string{"совета директоров", "наблюдательного совета"}},
+	{"Ценные бумаги", []string{"ценных бумаг", "облигаци", "акций", "купон"}},
+	{"Отчетность", []string{"отчет", "отчетност", "аудитор"}},
+	{"Существенные факты", []string{"существенн", "инсайдерск"}},
+}
+
+const OTHER_CATEGORY = "Прочее"
+
+const (
+	COMPANY_NAME_SELECTOR = "h2"
+	EVENT_ROW_SELECTOR    = "#cont_wrap table tr, table.zebra tr"
+	EVENT_LINK_SELECTOR   = "a[href*='event.aspx']"
+	FILE_ROW_SELECTOR     = "table.zebra tr, table.centerHeader tr"
+	FILE_LINK_SELECTOR    = "a[href*='FileLoad.ashx']"
+)
+
+var dateRe = regexp.MustCompile(`\d{2}\.\d{2}\.\d{4}( \d{2}:\d{2})?`)
+
+var VALID_COMPANY_ID_PATTERN = []*unicode.RangeTable{
+	unicode.Digit,
+}
+
+var VALID_KIND_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	{R16: []unicode.Range16{{',', ',', 1}}},
+}
+
+var VALID_CATEGORY_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.White_Space,
+	{R16: []unicode.Range16{{',', ',', 1}}},
+}
+
+var PARAMS = []utils.Param{
+	{Name: "company_id", Description: "Company id from e-disclosure.ru/portal/company.aspx?id=", Example: "934", Path: true},
+	{Name: "type", Description: "Comma separated kinds of items: events, documents", Example: KIND_EVENTS},
+	{Name: "category", Description: "Comma separated parts of event categories or document types to keep", Example: "Дивиденды"},
+}
+
+type Disclosure struct {
+	Title    string
+	URL      string
+	Date     time.Time
+	Category string
+	Details  string
+}
+
+// ParseKinds turns a comma separated type= value into a set of kinds, an
+// empty value allows every kind.
+func ParseKinds(s string) (mapset.Set[string], error) {
+	kinds := mapset.NewSet[string]()
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if !slices.Contains(KINDS, k) {
+			return nil, fmt.Errorf("unknown item type %s", k)
+		}
+		kinds.Add(k)
+	}
+	if kinds.Cardinality() == 0 {
+		kinds.Append(KINDS...)
+	}
+	return kinds, nil
+}
+
+// ParseCategories splits a comma separated category= value, an empty value
+// allows every category.
+func ParseCategories(s string) []string {
+	var categories []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
+
+func matchCategory(category string, categories []string) bool {
+	if len(categories) == 0 {
+		return true
+	}
+	category = strings.ToLower(category)
+	for _, c := range categories {
+		if strings.Contains(category, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// EventCategory sorts an event into a category by its title.
+func EventCategory(title string) string {
+	title = strings.ToLower(title)
+	for _, c := range EVENT_CATEGORIES {
+		for _, keyword := range c.Keywords {
+			if strings.Contains(title, keyword) {
+				return c.Category
+			}
+		}
+	}
+	return OTHER_CATEGORY
+}
+
+func parseDate(s string) (time.Time, error) {
+	layout := "02.01.2006"
+	if len(s) > len(layout) {
+		layout = "02.01.2006 15:04"
+	}
+	return time.ParseInLocation(layout, s, utils.SourceLocation(SOURCE_NAME, SOURCE_TIMEZONE))
+}
+
+func absoluteUrl(href string) string {
+	if strings.HasPrefix(href, "http") {
+		return href
+	}
+	return fmt.Sprintf("%s/%s", BASE_URL, strings.TrimPrefix(href, "/portal/"))
+}
+
+// rowDate finds the first date in the cells of a table row.
+func rowDate(row *goquery.Selection) time.Time {
+	var date time.Time
+	row.Find("td").EachWithBreak(func(_ int, td *goquery.Selection) bool {
+		match := dateRe.FindString(td.Text())
+		if match == "" {
+			return true
+		}
+		parsed, err := parseDate(match)
+		if err != nil {
+			log.Printf("failed to parse date: %s (%v)", match, err)
+			return true
+		}
+		date = parsed
+		return false
+	})
+	return date
+}
+
+func companyName(doc *goquery.Document) string {
+	return strings.Join(strings.Fields(doc.Find(COMPANY_NAME_SELECTOR).First().Text()), " ")
+}
+
+func GetEvents(companyId string) (string, []Disclosure, error) {
+	doc, err := utils.FetchDocument(fmt.Sprintf("%s/events.aspx?id=%s", BASE_URL, companyId))
+	if err != nil {
+		return "", []Disclosure{}, err
+	}
+
+	var results []Disclosure
+	doc.Find(EVENT_ROW_SELECTOR).Each(func(_ int, row *goquery.Selection) {
+		a := row.Find(EVENT_LINK_SELECTOR).First()
+		href, ok := a.Attr("href")
+		if !ok {
+			return
+		}
+
+		title := strings.Join(strings.Fields(a.Text()), " ")
+		results = append(results, Disclosure{
+			Title:    title,
+			URL:      absoluteUrl(href),
+			Date:     rowDate(row),
+			Category: EventCategory(title),
+		})
+	})
+	return companyName(doc), results, nil
+}
+
+func getDocumentsByType(companyId string, typeId int, typeName string) ([]Disclosure, error) {
+	doc, err := utils.FetchDocument(fmt.Sprintf("%s/files.aspx?id=%s&type=%d", BASE_URL, companyId, typeId))
+	if err != nil {
+		return []Disclosure{}, err
+	}
+
+	var results []Disclosure
+	doc.Find(FILE_ROW_SELECTOR).Each(func(_ int, row *goquery.Selection) {
+		href, ok := row.Find(FILE_LINK_SELECTOR).First().Attr("href")
+		if !ok {
+			return
+		}
+
+		// the cells hold the document type, the reporting period and the file size
+		var cells []string
+		row.Find("td").Each(func(_ int, td *goquery.Selection) {
+			text := strings.Join(strings.Fields(td.Text()), " ")
+			if text != "" && !dateRe.MatchString(text) {
+				cells = append(cells, text)
+			}
+		})
+
+		title := typeName
+		if len(cells) > 1 {
+			title = strings.Join(cells[1:len(cells)-1], ", ")
+		}
+		if title == "" {
+			title = typeName
+		}
+
+		results = append(results, Disclosure{
+			Title:    title,
+			URL:      absoluteUrl(href),
+			Date:     rowDate(row),
+			Category: typeName,
+			Details:  strings.Join(cells, " · "),
+		})
+	})
+	return results, nil
+}
+
+func GetDocuments(companyId string, categories []string) []Disclosure {
+	documents := make([][]Disclosure, len(DOCUMENT_TYPES))
+	utils.Parallel(len(DOCUMENT_TYPES), DOCUMENT_WORKERS, func(i int) {
+		documentType := DOCUMENT_TYPES[i]
+		if !matchCategory(documentType.Name, categories) {
+			return
+		}
+		results, err := getDocumentsByType(companyId, documentType.ID, documentType.Name)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		documents[i] = results
+	})
+
+	var results []Disclosure
+	for _, d := range documents {
+		results = append(results, d...)
+	}
+	return results
+}
+
+// FeedPath maps e-disclosure.ru company, events and files pages to the
+// bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	if u.Host != "e-disclosure.ru" {
+		return "", false
+	}
+	switch strings.TrimPrefix(strings.ToLower(u.Path), "/portal/") {
+	case "company.aspx", "events.aspx", "files.aspx":
+	default:
+		return "", false
+	}
+
+	id := u.Query().Get("id")
+	companyId := utils.StringsAllowlist(id, VALID_COMPANY_ID_PATTERN)
+	if companyId == "" || companyId != id {
+		return "", false
+	}
+	return fmt.Sprintf("/e-disclosure/%s", companyId), true
+}
+
+func GetFeed(companyId string, kinds mapset.Set[string], categories []string) (*utils.Feed, error) {
+	var name string
+	var results []Disclosure
+
+	if kinds.Contains(KIND_EVENTS) {
+		companyName, events, err := GetEvents(companyId)
+		if err != nil {
+			return nil, err
+		}
+		name = companyName
+		for _, event := range events {
+			if matchCategory(event.Category, categories) {
+				results = append(results, event)
+			}
+		}
+	}
+
+	if kinds.Contains(KIND_DOCUMENTS) {
+		results = append(results, GetDocuments(companyId, categories)...)
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no disclosures")
+	}
+
+	if name == "" {
+		name = companyId
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: fmt.Sprintf("E-disclosure %s", name),
+		Link: &feeds.Link{
+			Href: fmt.Sprintf("%s/company.aspx?id=%s", BASE_URL, companyId),
+		},
+		Description: fmt.Sprintf("Раскрытие информации %s", name),
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, entry := range results {
+
+		url := entry.URL
+
+		if seenSet.Contains(url) {
+			continue
+		}
+
+		seenSet.Add(url)
+		item := &feeds.Item{
+			Title:   entry.Title,
+			Link:    &feeds.Link{Href: url},
+			Created: entry.Date,
+			Id:      url,
+		}
+		if entry.Details != "" {
+			item.Description = fmt.Sprintf("<p>%s</p>", html.EscapeString(entry.Details))
+		}
+		feed.Items = append(feed.Items, item)
+		feed.SetCategory(url, entry.Category)
+	}
+
+	feed.Sort(func(a, b *feeds.Item) bool {
+		return a.Created.After(b.Created)
+	})
+
+	return feed, nil
+}
@@ -0,0 +1,217 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// Filter narrows down the items of any bridge's feed. Zero values disable
+// the corresponding check.
+type Filter struct {
+	Include     []string
+	Exclude     []string
+	IncludeRe   *regexp.Regexp
+	ExcludeRe   *regexp.Regexp
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	SkipBefore  time.Time
+	SkipAfter   time.Time
+	MaxAge      time.Duration
+	Limit       int
+}
+
+// PARAMS lists the query parameters understood by Parse.
+var PARAMS = []utils.Param{
+	{Name: "include", Description: "Comma separated keywords, keep items mentioning any of them", Example: "обзор,интервью"},
+	{Name: "exclude", Description: "Comma separated keywords, drop items mentioning any of them", Example: "реклама"},
+	{Name: "include_re", Description: "Keep items whose title or description match the regexp", Example: `^Выпуск \d+`},
+	{Name: "exclude_re", Description: "Drop items whose title or description match the regexp", Example: "#shorts"},
+	{Name: "min_duration", Description: "Drop videos shorter than this, seconds or 10m, 1h", Example: "120"},
+	{Name: "max_duration", Description: "Drop videos longer than this, seconds or 10m, 1h", Example: "1h"},
+	{Name: "skip_before", Description: "Drop items published before this unix time or date", Example: "2024-01-01"},
+	{Name: "skip_after", Description: "Drop items published after this unix time or date", Example: "1735689600"},
+	{Name: "max_age", Description: "Drop items older than this, e.g. 7d or 48h", Example: "7d"},
+	{Name: "limit", Description: "Keep at most this many items", Example: "20"},
+}
+
+func parseKeywords(s string) []string {
+	var keywords []string
+	for _, keyword := range strings.Split(s, ",") {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+func parseRegexp(s string) (*regexp.Regexp, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return regexp.Compile("(?i)" + s)
+}
+
+// ParseDuration accepts plain seconds, days like "7d" or anything
+// time.ParseDuration understands.
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// parseTimestamp accepts unix seconds or an RFC 3339 date.
+func parseTimestamp(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func Parse(query url.Values) (Filter, error) {
+	var f Filter
+	var err error
+
+	f.Include = parseKeywords(query.Get("include"))
+	f.Exclude = parseKeywords(query.Get("exclude"))
+
+	if f.IncludeRe, err = parseRegexp(query.Get("include_re")); err != nil {
+		return Filter{}, fmt.Errorf("include_re: %w", err)
+	}
+	if f.ExcludeRe, err = parseRegexp(query.Get("exclude_re")); err != nil {
+		return Filter{}, fmt.Errorf("exclude_re: %w", err)
+	}
+
+	if f.MinDuration, err = ParseDuration(strings.TrimSpace(query.Get("min_duration"))); err != nil {
+		return Filter{}, fmt.Errorf("min_duration: %w", err)
+	}
+	if f.MaxDuration, err = ParseDuration(strings.TrimSpace(query.Get("max_duration"))); err != nil {
+		return Filter{}, fmt.Errorf("max_duration: %w", err)
+	}
+	if f.MaxAge, err = ParseDuration(strings.TrimSpace(query.Get("max_age"))); err != nil {
+		return Filter{}, fmt.Errorf("max_age: %w", err)
+	}
+
+	if f.SkipBefore, err = parseTimestamp(strings.TrimSpace(query.Get("skip_before"))); err != nil {
+		return Filter{}, fmt.Errorf("skip_before: %w", err)
+	}
+	if f.SkipAfter, err = parseTimestamp(strings.TrimSpace(query.Get("skip_after"))); err != nil {
+		return Filter{}, fmt.Errorf("skip_after: %w", err)
+	}
+
+	if limit := strings.TrimSpace(query.Get("limit")); limit != "" {
+		if f.Limit, err = strconv.Atoi(limit); err != nil || f.Limit < 0 {
+			return Filter{}, fmt.Errorf("limit: invalid value %s", limit)
+		}
+	}
+
+	return f, nil
+}
+
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match tells whether an item passes the filter. Items with an unknown date
+// or duration are never dropped by date or duration checks.
+func (f Filter) Match(item *feeds.Item, duration time.Duration, now time.Time) bool {
+	text := item.Title + "\n" + utils.HtmlToText(item.Description)
+	lowered := strings.ToLower(text)
+
+	if len(f.Include) > 0 && !containsAny(lowered, f.Include) {
+		return false
+	}
+	if containsAny(lowered, f.Exclude) {
+		return false
+	}
+	if f.IncludeRe != nil && !f.IncludeRe.MatchString(text) {
+		return false
+	}
+	if f.ExcludeRe != nil && f.ExcludeRe.MatchString(text) {
+		return false
+	}
+
+	if duration > 0 {
+		if f.MinDuration > 0 && duration < f.MinDuration {
+			return false
+		}
+		if f.MaxDuration > 0 && duration > f.MaxDuration {
+			return false
+		}
+	}
+
+	created := item.Created
+	if created.IsZero() {
+		created = item.Updated
+	}
+	if !created.IsZero() {
+		if !f.SkipBefore.IsZero() && created.Before(f.SkipBefore) {
+			return false
+		}
+		if !f.SkipAfter.IsZero() && created.After(f.SkipAfter) {
+			return false
+		}
+		if f.MaxAge > 0 && now.Sub(created) > f.MaxAge {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f Filter) match(feed *utils.Feed, limit int) []*feeds.Item {
+	now := time.Now()
+	var items []*feeds.Item
+	for _, item := range feed.Items {
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+		if f.Match(item, feed.Durations[item.Id], now) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Apply drops the items of a feed not matching the filter, in place, and
+// enriches the rest. Enrichment can fill in dates and text the first pass
+// did not see, so the survivors are matched once more.
+func (f Filter) Apply(feed *utils.Feed) {
+	feed.Items = f.match(feed, f.Limit)
+	if feed.Enrich == nil {
+		return
+	}
+
+	feed.Enrich(feed.Items)
+	feed.Enrich = nil
+	feed.Items = f.match(feed, 0)
+}
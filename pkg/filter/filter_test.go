@@ -0,0 +1,153 @@
+package filter
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"xd", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"", false},
+		{"include=a,b&exclude=c&limit=5", false},
+		{"skip_before=2024-01-01&skip_after=1735689600&max_age=7d", false},
+		{"include_re=(", true},
+		{"exclude_re=[", true},
+		{"min_duration=long", true},
+		{"skip_before=yesterday", true},
+		{"limit=-1", true},
+		{"limit=many", true},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		_, err := Parse(query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+		}
+	}
+
+	query, _ := url.ParseQuery("include= Обзор , ,Интервью&limit=3")
+	f, err := Parse(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Include) != 2 || f.Include[0] != "обзор" || f.Include[1] != "интервью" {
+		t.Errorf("Include = %q, want [обзор интервью]", f.Include)
+	}
+	if f.Limit != 3 {
+		t.Errorf("Limit = %d, want 3", f.Limit)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	item := &feeds.Item{
+		Title:       "Обзор новинок",
+		Description: "<p>Выпуск 12 #shorts</p>",
+		Created:     now.Add(-48 * time.Hour),
+	}
+	undated := &feeds.Item{Title: "Обзор без даты"}
+
+	tests := []struct {
+		name     string
+		query    string
+		item     *feeds.Item
+		duration time.Duration
+		want     bool
+	}{
+		{"empty filter", "", item, 0, true},
+		{"include matches title", "include=обзор", item, 0, true},
+		{"include matches description", "include=выпуск", item, 0, true},
+		{"include misses", "include=интервью", item, 0, false},
+		{"exclude", "exclude=shorts", item, 0, false},
+		{"include_re is case insensitive", "include_re=^обзор", item, 0, true},
+		{"exclude_re", "exclude_re=%23shorts", item, 0, false},
+		{"too short", "min_duration=10m", item, 5 * time.Minute, false},
+		{"too long", "max_duration=1h", item, 2 * time.Hour, false},
+		{"unknown duration kept", "min_duration=10m", item, 0, true},
+		{"too old", "max_age=1d", item, 0, false},
+		{"recent enough", "max_age=3d", item, 0, true},
+		{"before skip_before", "skip_before=2025-06-01", item, 0, false},
+		{"after skip_after", "skip_after=2025-05-01", item, 0, false},
+		{"unknown date kept", "max_age=1d", undated, 0, true},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		f, err := Parse(query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := f.Match(tt.item, tt.duration, now); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEnrichesOnlyKeptItems(t *testing.T) {
+	feed := utils.NewFeed(&feeds.Feed{})
+	for _, title := range []string{"a1", "b1", "a2", "a3", "a4"} {
+		feed.Items = append(feed.Items, &feeds.Item{Title: title, Id: title})
+	}
+
+	var enriched []string
+	feed.Enrich = func(items []*feeds.Item) {
+		for _, item := range items {
+			enriched = append(enriched, item.Id)
+			// enrichment may reveal text the first pass did not see
+			if item.Id == "a2" {
+				item.Description = "реклама"
+			}
+		}
+	}
+
+	query, _ := url.ParseQuery("include=a&exclude=реклама&limit=3")
+	f, err := Parse(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Apply(feed)
+
+	if len(enriched) != 3 || enriched[0] != "a1" || enriched[1] != "a2" || enriched[2] != "a3" {
+		t.Errorf("enriched %q, want [a1 a2 a3]", enriched)
+	}
+	if len(feed.Items) != 2 || feed.Items[0].Id != "a1" || feed.Items[1].Id != "a3" {
+		t.Errorf("kept %d items, want a1 and a3", len(feed.Items))
+	}
+	if feed.Enrich != nil {
+		t.Error("Enrich was not cleared")
+	}
+}
@@ -0,0 +1,258 @@
+package fundmanager
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/accentAm"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/documents"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/scraper"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// placeholder for the fund name in SelectorConfig.FundUrl
+const FUND_PLACEHOLDER = "{fund}"
+
+// fund names may contain dots, e.g. in the page file name
+var VALID_FUND_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	{R16: []unicode.Range16{{'_', '_', 1}}},
+	{R16: []unicode.Range16{{'-', '-', 1}}},
+	{R16: []unicode.Range16{{'.', '.', 1}}},
+}
+
+var VALID_SECTION_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	unicode.White_Space,
+	{R16: []unicode.Range16{{'-', '-', 1}}},
+}
+
+var VALID_PARAGRAPHS_PATTERN = []*unicode.RangeTable{
+	unicode.Digit,
+}
+
+const SECTION_ALL = "all"
+
+var PARAMS = []utils.Param{
+	{Name: "manager", Description: "Management company, accent-am or one from the config", Example: accentAm.SOURCE_NAME, Path: true},
+	{Name: "fund_name", Description: "Fund name as in the address of its page", Example: "aktsent-5-fond-nedvizhimosti", Path: true},
+	{Name: "section", Description: "Part of a document section name, or all, the manager's default if empty", Example: accentAm.DEFAULT_SECTION},
+	{Name: "paragraphs", Description: "Paragraphs of document text to include, 0 to skip downloading documents", Example: "3"},
+}
+
+// Disclosure is a document published about a fund, every manager reports
+// its funds in this shape.
+type Disclosure struct {
+	Title   string
+	URL     string
+	Date    time.Time
+	Section string
+}
+
+// Manager reads the disclosures of the funds of one management company.
+type Manager struct {
+	Name  string
+	Title string
+	// FundUrl is the page of a fund on the manager's site
+	FundUrl func(fund string) string
+	// GetDisclosures lists the latest documents of a fund, an empty
+	// section means the manager's default one
+	GetDisclosures func(fund, section string) ([]Disclosure, error)
+	// Documents means every disclosure links to a document, otherwise
+	// only links with documents.DOCUMENT_EXTENSIONS are downloaded
+	Documents bool
+}
+
+// SelectorConfig describes a manager whose fund news pages can be read with
+// CSS selectors, Section optionally finds the section name in every item.
+type SelectorConfig struct {
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	FundUrl string `json:"fund_url"`
+	scraper.Selectors
+	Section string `json:"section"`
+}
+
+var ACCENT_AM = Manager{
+	Name:  accentAm.SOURCE_NAME,
+	Title: "Акцент",
+	FundUrl: func(fund string) string {
+		return fmt.Sprintf("%s/funds/%s", accentAm.BASE_URL, fund)
+	},
+	GetDisclosures: func(fund, section string) ([]Disclosure, error) {
+		if section == "" {
+			section = accentAm.DEFAULT_SECTION
+		}
+		messages, err := accentAm.GetLatestMessagesByFundName(fund, section)
+		if err != nil {
+			return []Disclosure{}, err
+		}
+
+		var results []Disclosure
+		for _, message := range messages {
+			results = append(results, Disclosure{
+				Title:   message.Title,
+				URL:     message.URL,
+				Date:    message.Date,
+				Section: message.Section,
+			})
+		}
+		return results, nil
+	},
+	Documents: true,
+}
+
+// matchSection accepts an empty query, "all" or a case-insensitive part of
+// the section name.
+func matchSection(section, query string) bool {
+	if query == "" || query == SECTION_ALL {
+		return true
+	}
+	return strings.Contains(strings.ToLower(section), strings.ToLower(query))
+}
+
+func getSelectorDisclosures(c SelectorConfig, fund, section string) ([]Disclosure, error) {
+	pageUrl := strings.ReplaceAll(c.FundUrl, FUND_PLACEHOLDER, url.PathEscape(fund))
+	items, err := scraper.Scrape(pageUrl, c.Selectors, utils.SourceLocation(c.Name, utils.MOSCOW_TIMEZONE))
+	if err != nil {
+		return []Disclosure{}, err
+	}
+
+	var results []Disclosure
+	for _, item := range items {
+		disclosure := Disclosure{
+			Title:   item.Title,
+			URL:     item.URL,
+			Date:    item.Date,
+			Section: c.Title,
+		}
+		if c.Section != "" {
+			disclosure.Section = strings.TrimSpace(item.Selection.Find(c.Section).First().Text())
+		}
+
+		if matchSection(disclosure.Section, section) {
+			results = append(results, disclosure)
+		}
+	}
+	return results, nil
+}
+
+// NewSelectorManager checks a SelectorConfig and builds a Manager from it.
+func NewSelectorManager(c SelectorConfig) (Manager, error) {
+	name := utils.StringsAllowlist(c.Name, utils.VALID_NAME_PATTERN)
+	if name == "" || name != c.Name {
+		return Manager{}, fmt.Errorf("invalid fund manager name %q", c.Name)
+	}
+	if !strings.Contains(c.FundUrl, FUND_PLACEHOLDER) {
+		return Manager{}, fmt.Errorf("fund manager %s: fund_url has no %s", c.Name, FUND_PLACEHOLDER)
+	}
+	if c.Item == "" {
+		return Manager{}, fmt.Errorf("fund manager %s: empty item selector", c.Name)
+	}
+	if c.Title == "" {
+		c.Title = c.Name
+	}
+
+	return Manager{
+		Name:  c.Name,
+		Title: c.Title,
+		FundUrl: func(fund string) string {
+			return strings.ReplaceAll(c.FundUrl, FUND_PLACEHOLDER, url.PathEscape(fund))
+		},
+		GetDisclosures: func(fund, section string) ([]Disclosure, error) {
+			return getSelectorDisclosures(c, fund, section)
+		},
+	}, nil
+}
+
+// Managers returns the built-in managers followed by the ones from the
+// config, invalid configs are logged and skipped.
+func Managers(configs []SelectorConfig) []Manager {
+	managers := []Manager{ACCENT_AM}
+	for _, c := range configs {
+		if _, ok := utils.FindByName(managers, c.Name); ok {
+			log.Printf("fund manager %s is already defined", c.Name)
+			continue
+		}
+		manager, err := NewSelectorManager(c)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		managers = append(managers, manager)
+	}
+	return managers
+}
+
+func (m Manager) GetName() string {
+	return m.Name
+}
+
+func GetFeed(manager Manager, fund string, section string, paragraphs int) (*utils.Feed, error) {
+	results, err := manager.GetDisclosures(fund, section)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no disclosures")
+	}
+
+	title := fmt.Sprintf("%s @%s", manager.Title, fund)
+	if section != "" {
+		title = fmt.Sprintf("%s (%s)", title, section)
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: title,
+		Link: &feeds.Link{
+			Href: manager.FundUrl(fund),
+		},
+		Description: fmt.Sprintf("Документы фонда %s, %s", fund, manager.Title),
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, entry := range results {
+
+		url := entry.URL
+
+		if seenSet.Contains(url) {
+			continue
+		}
+
+		seenSet.Add(url)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:   entry.Title,
+			Link:    &feeds.Link{Href: url},
+			Author:  &feeds.Author{Name: manager.Title},
+			Created: entry.Date,
+			Id:      url,
+		})
+		feed.SetCategory(url, entry.Section)
+	}
+
+	feed.Enrich = func(items []*feeds.Item) {
+		if manager.Documents {
+			documents.AttachItems(items, paragraphs)
+			return
+		}
+
+		var linked []*feeds.Item
+		for _, item := range items {
+			if documents.IsDocumentUrl(item.Link.Href) {
+				linked = append(linked, item)
+			}
+		}
+		documents.AttachItems(linked, paragraphs)
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,93 @@
+package habr
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/articles"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// https://habr.com/ru/users/varanio/publications/articles/
+// https://habr.com/ru/hubs/go/articles/
+
+const HABR_SITE = "https://habr.com"
+
+const (
+	KIND_USER = "user"
+	KIND_HUB  = "hub"
+)
+
+var VALID_NAME_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	{R16: []unicode.Range16{{'_', '_', 1}}},
+	{R16: []unicode.Range16{{'-', '-', 1}}},
+}
+
+var USER_PARAMS = []utils.Param{
+	{Name: "name", Description: "Username from habr.com/ru/users/name/", Example: "varanio", Path: true},
+}
+
+var HUB_PARAMS = []utils.Param{
+	{Name: "name", Description: "Hub name from habr.com/ru/hubs/name/", Example: "go", Path: true},
+}
+
+var SITE = articles.Site{
+	BaseUrl:   HABR_SITE,
+	Item:      "article.tm-articles-list__item",
+	Link:      "a.tm-title__link",
+	Author:    ".tm-user-info__username",
+	Date:      "time",
+	Lead:      ".article-formatted-body",
+	Tags:      ".tm-publication-hub__link span:first-child",
+	TagsLabel: "Хабы",
+	Rating:    ".tm-votes-meter__value",
+	Comments:  ".tm-article-comments-counter-link__value",
+	Body:      []string{"#post-content-body", ".article-formatted-body"},
+}
+
+func listUrl(kind, name string) string {
+	if kind == KIND_HUB {
+		return fmt.Sprintf("%s/ru/hubs/%s/articles/", HABR_SITE, name)
+	}
+	return fmt.Sprintf("%s/ru/users/%s/publications/articles/", HABR_SITE, name)
+}
+
+// FeedPath maps habr.com user and hub pages to the bridge routes.
+func FeedPath(u *url.URL) (string, bool) {
+	if u.Host != "habr.com" {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	// the language prefix is optional
+	if len(parts) > 0 && (parts[0] == "ru" || parts[0] == "en") {
+		parts = parts[1:]
+	}
+	if len(parts) < 2 {
+		return "", false
+	}
+
+	name := utils.StringsAllowlist(parts[1], VALID_NAME_PATTERN)
+	if name == "" || name != parts[1] {
+		return "", false
+	}
+
+	switch parts[0] {
+	case "users":
+		return fmt.Sprintf("/habr/user/%s", name), true
+	case "hubs", "hub":
+		return fmt.Sprintf("/habr/hub/%s", name), true
+	}
+	return "", false
+}
+
+func GetFeed(kind, name string) (*utils.Feed, error) {
+	title := fmt.Sprintf("Habr @%s", name)
+	if kind == KIND_HUB {
+		title = fmt.Sprintf("Habr хаб %s", name)
+	}
+	return articles.GetFeed(SITE, title, listUrl(kind, name))
+}
@@ -0,0 +1,304 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+const MAX_RESPONSE_SIZE = 16 << 20
+
+// date_layout values for numeric timestamps, other values are Go layouts
+const (
+	DATE_UNIX    = "unix"
+	DATE_UNIX_MS = "unix_ms"
+)
+
+var METHODS = []string{http.MethodGet, http.MethodPost}
+
+// {path} placeholders in a link template, read from the item
+var fieldPlaceholderRe = regexp.MustCompile(`\{([^{}]+)\}`)
+
+var PARAMS = []utils.Param{
+	{Name: "api", Description: "API name from the config, its url placeholders are filled from the query string", Example: "example", Path: true},
+}
+
+// Fields maps item fields to JSONPaths inside every item. LinkTemplate
+// builds the link from {path} placeholders when the item has no full link.
+type Fields struct {
+	Title           string `json:"title"`
+	Link            string `json:"link"`
+	LinkTemplate    string `json:"link_template"`
+	Description     string `json:"description"`
+	DescriptionHtml bool   `json:"description_html"`
+	Image           string `json:"image"`
+	Author          string `json:"author"`
+	Date            string `json:"date"`
+	DateLayout      string `json:"date_layout"`
+	Duration        string `json:"duration"`
+}
+
+// Api is a JSON endpoint described in the config and turned into a feed,
+// Url and Body take {name} placeholders from the query string.
+type Api struct {
+	Name     string            `json:"name"`
+	Title    string            `json:"title"`
+	Url      string            `json:"url"`
+	Method   string            `json:"method"`
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body"`
+	Items    string            `json:"items"`
+	Fields   Fields            `json:"fields"`
+	Timezone string            `json:"timezone"`
+}
+
+func (a Api) validate() error {
+	name := utils.StringsAllowlist(a.Name, utils.VALID_NAME_PATTERN)
+	if name == "" || name != a.Name {
+		return fmt.Errorf("invalid api name %q", a.Name)
+	}
+	if !strings.HasPrefix(a.Url, "http://") && !strings.HasPrefix(a.Url, "https://") {
+		return fmt.Errorf("api %s: url is not http(s)", a.Name)
+	}
+	if !slices.Contains(METHODS, a.Method) {
+		return fmt.Errorf("api %s: unsupported method %s", a.Name, a.Method)
+	}
+	if _, err := parsePath(a.Items); err != nil {
+		return fmt.Errorf("api %s: items: %v", a.Name, err)
+	}
+	if a.Fields.Link == "" && a.Fields.LinkTemplate == "" {
+		return fmt.Errorf("api %s: no link or link_template field", a.Name)
+	}
+	if _, err := time.LoadLocation(a.Timezone); err != nil {
+		return fmt.Errorf("api %s: %v", a.Name, err)
+	}
+	return nil
+}
+
+// Apis checks the APIs from the config, invalid ones are logged and
+// skipped.
+func Apis(configs []Api) []Api {
+	var apis []Api
+	for _, api := range configs {
+		if _, ok := utils.FindByName(apis, api.Name); ok {
+			log.Printf("api %s is already defined", api.Name)
+			continue
+		}
+		api.Method = strings.ToUpper(api.Method)
+		if api.Method == "" {
+			api.Method = http.MethodGet
+		}
+		if api.Timezone == "" {
+			api.Timezone = utils.MOSCOW_TIMEZONE
+		}
+		if err := api.validate(); err != nil {
+			log.Println(err)
+			continue
+		}
+		if api.Title == "" {
+			api.Title = api.Name
+		}
+		apis = append(apis, api)
+	}
+	return apis
+}
+
+func (a Api) GetName() string {
+	return a.Name
+}
+
+// Fetch requests the API and decodes the response, numbers are kept as
+// json.Number so ids do not turn into floats.
+func Fetch(api Api, vars url.Values) (string, any, error) {
+	apiUrl, err := utils.FillTemplate(api.Url, vars, url.PathEscape)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var body io.Reader
+	if api.Body != "" {
+		filled, err := utils.FillTemplate(api.Body, vars, utils.JsonEscape)
+		if err != nil {
+			return "", nil, err
+		}
+		body = strings.NewReader(filled)
+	}
+
+	req, err := http.NewRequest(api.Method, apiUrl, body)
+	if err != nil {
+		log.Println(err)
+		return "", nil, err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+	req.Header.Set("Accept", "application/json")
+	for key, value := range api.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("got status %d for %s", resp.StatusCode, apiUrl)
+	}
+
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, MAX_RESPONSE_SIZE))
+	decoder.UseNumber()
+	var j any
+	err = decoder.Decode(&j)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return apiUrl, j, nil
+}
+
+func parseDate(s, layout string, loc *time.Location) (time.Time, error) {
+	switch layout {
+	case "":
+		return time.Parse(time.RFC3339, s)
+	case DATE_UNIX, DATE_UNIX_MS:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if layout == DATE_UNIX_MS {
+			return time.UnixMilli(int64(n)), nil
+		}
+		return time.Unix(int64(n), 0), nil
+	}
+	return time.ParseInLocation(layout, s, loc)
+}
+
+// fillTemplate replaces {path} placeholders with path-escaped values of
+// the item.
+func fillTemplate(template string, item any) string {
+	return fieldPlaceholderRe.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return url.PathEscape(LookupString(item, placeholder[1:len(placeholder)-1]))
+	})
+}
+
+func itemDescription(fields Fields, item any, baseUrl string) string {
+	var b strings.Builder
+	if image := LookupString(item, fields.Image); image != "" {
+		fmt.Fprintf(&b, `<p><img src="%s"></p>`, html.EscapeString(image))
+	}
+
+	description := LookupString(item, fields.Description)
+	if fields.DescriptionHtml {
+		sanitized, err := utils.SanitizeHtml(description, baseUrl)
+		if err != nil {
+			log.Println(err)
+		}
+		b.WriteString(sanitized)
+	} else {
+		b.WriteString(utils.TextToHtml(description, nil))
+	}
+	return b.String()
+}
+
+func GetFeed(api Api, vars url.Values) (*utils.Feed, error) {
+	apiUrl, response, err := Fetch(api, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := Lookup(response, api.Items)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := found.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an array", api.Items)
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items")
+	}
+
+	base, err := url.Parse(apiUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: api.Title,
+		Link: &feeds.Link{
+			Href: fmt.Sprintf("%s://%s", base.Scheme, base.Host),
+		},
+		Description: fmt.Sprintf("Лента RSS %s", api.Title),
+	})
+
+	loc := utils.SourceLocation(api.Name, api.Timezone)
+	seenSet := mapset.NewSet[string]()
+	for _, entry := range items {
+		link := LookupString(entry, api.Fields.Link)
+		if link == "" && api.Fields.LinkTemplate != "" {
+			link = fillTemplate(api.Fields.LinkTemplate, entry)
+		}
+		if link == "" {
+			continue
+		}
+		link, ok := utils.ResolveUrl(base, link)
+		if !ok {
+			continue
+		}
+
+		if seenSet.Contains(link) {
+			continue
+		}
+
+		item := &feeds.Item{
+			Title:       LookupString(entry, api.Fields.Title),
+			Link:        &feeds.Link{Href: link},
+			Description: itemDescription(api.Fields, entry, link),
+			Id:          link,
+		}
+		if item.Title == "" {
+			text := strings.TrimSpace(utils.HtmlToText(item.Description))
+			item.Title = utils.Truncate(strings.SplitN(text, "\n", 2)[0], 80)
+		}
+		if author := LookupString(entry, api.Fields.Author); author != "" {
+			item.Author = &feeds.Author{Name: author}
+		}
+		if dateStr := LookupString(entry, api.Fields.Date); dateStr != "" {
+			item.Created, err = parseDate(dateStr, api.Fields.DateLayout, loc)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+
+		seenSet.Add(link)
+		feed.Items = append(feed.Items, item)
+
+		if durationStr := LookupString(entry, api.Fields.Duration); durationStr != "" {
+			seconds, err := strconv.ParseFloat(durationStr, 64)
+			if err != nil {
+				log.Println(err)
+			} else {
+				feed.SetDuration(link, time.Duration(seconds*float64(time.Second)))
+			}
+		}
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,104 @@
+package jsonapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePath splits a JSONPath like $.data.items[0].title into object keys
+// and array indexes, only this dotted subset of JSONPath is supported.
+func parsePath(path string) ([]any, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	var steps []any
+	for path != "" {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in json path")
+			}
+			steps = append(steps, path[:end])
+			path = path[end:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed [ in json path")
+			}
+			inner := strings.Trim(path[1:end], `'"`)
+			if index, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, index)
+			} else {
+				steps = append(steps, inner)
+			}
+			path = path[end+1:]
+		default:
+			// a path may start without $.
+			if len(steps) > 0 {
+				return nil, fmt.Errorf("unexpected %q in json path", path[0])
+			}
+			path = "." + path
+		}
+	}
+	return steps, nil
+}
+
+// Lookup walks a decoded JSON value along a path.
+func Lookup(value any, path string) (any, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range steps {
+		switch step := step.(type) {
+		case string:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: not an object at %s", path, step)
+			}
+			value, ok = object[step]
+			if !ok {
+				return nil, fmt.Errorf("%s: no key %s", path, step)
+			}
+		case int:
+			array, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: not an array at [%d]", path, step)
+			}
+			if step < 0 {
+				step += len(array)
+			}
+			if step < 0 || step >= len(array) {
+				return nil, fmt.Errorf("%s: index %d out of range", path, step)
+			}
+			value = array[step]
+		}
+	}
+	return value, nil
+}
+
+// LookupString reads a scalar as a string, a missing value is empty.
+func LookupString(value any, path string) string {
+	if path == "" {
+		return ""
+	}
+	found, err := Lookup(value, path)
+	if err != nil {
+		return ""
+	}
+	switch found := found.(type) {
+	case string:
+		return found
+	case nil, map[string]any, []any:
+		return ""
+	default:
+		return fmt.Sprint(found)
+	}
+}
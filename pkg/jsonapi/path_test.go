@@ -0,0 +1,115 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []any
+		wantErr bool
+	}{
+		{"$", nil, false},
+		{"$.data.items", []any{"data", "items"}, false},
+		{"data.items", []any{"data", "items"}, false},
+		{"$.items[0].title", []any{"items", 0, "title"}, false},
+		{"$.items[-1]", []any{"items", -1}, false},
+		{`$["odd key"].value`, []any{"odd key", "value"}, false},
+		{"$['a'][2]", []any{"a", 2}, false},
+		{" $.a ", []any{"a"}, false},
+		{"$.a..b", nil, true},
+		{"$.a[0", nil, true},
+		{"$.a[0]b", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePath(%q) = %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+}
+
+const testResponse = `{
+	"data": {
+		"items": [
+			{"id": 12345678901234567, "title": "First", "tags": ["a", "b"], "author": {"name": "Ann"}, "draft": false},
+			{"id": 2, "title": "Second", "author": null}
+		]
+	}
+}`
+
+func TestLookup(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(testResponse))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"$.data.items[0].title", "First", false},
+		{"$.data.items[-1].title", "Second", false},
+		{"$.data.items[0].author.name", "Ann", false},
+		{"$.data.items[0].tags[1]", "b", false},
+		// numbers keep their digits
+		{"$.data.items[0].id", "12345678901234567", false},
+		{"$.data.items[0].draft", "false", false},
+		{"$.data.items[2]", "", true},
+		{"$.data.items.title", "", true},
+		{"$.data[0]", "", true},
+		{"$.data.missing", "", true},
+		{"$.data.items[1].author.name", "", true},
+	}
+
+	for _, tt := range tests {
+		_, err := Lookup(value, tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Lookup(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+		if got := LookupString(value, tt.path); got != tt.want {
+			t.Errorf("LookupString(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if got := LookupString(value, "$.data.items[0].author"); got != "" {
+		t.Errorf("LookupString of an object = %q, want empty", got)
+	}
+	if got := LookupString(value, ""); got != "" {
+		t.Errorf("LookupString of an empty path = %q, want empty", got)
+	}
+}
+
+func TestFillTemplate(t *testing.T) {
+	item := map[string]any{
+		"id":      json.Number("42"),
+		"slug":    "a b?c#d/e",
+		"channel": map[string]any{"slug": "news"},
+	}
+
+	tests := []struct {
+		template, want string
+	}{
+		{"https://example.ru/{channel.slug}/{id}", "https://example.ru/news/42"},
+		{"https://example.ru/p/{slug}", "https://example.ru/p/a%20b%3Fc%23d%2Fe"},
+		{"https://example.ru/{missing}/{id}", "https://example.ru//42"},
+	}
+
+	for _, tt := range tests {
+		if got := fillTemplate(tt.template, item); got != tt.want {
+			t.Errorf("fillTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
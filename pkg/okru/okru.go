@@ -0,0 +1,217 @@
+package okru
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// https://ok.ru/video/c1234567
+
+const OKRU_SITE = "https://ok.ru"
+
+const DATE_WORKERS = 4
+
+// channel page and video page markup, kept together since the site changes it often
+const (
+	CHANNEL_TITLE_SELECTOR = "h1, .video-channel_h_name"
+	CARD_SELECTOR          = ".video-card"
+	CARD_LINK_SELECTOR     = "a.video-card_n, a.video-card_lk"
+	CARD_IMAGE_SELECTOR    = "img.video-card_img, img"
+	CARD_DURATION_SELECTOR = ".video-card_duration"
+)
+
+// where a video page states its publish date, the first one found wins
+var UPLOAD_DATE_SELECTORS = []string{
+	`meta[itemprop="uploadDate"]`,
+	`meta[property="video:release_date"]`,
+	`meta[property="ya:ovs:upload_date"]`,
+}
+
+// publish dates do not change, so keep them for a long time
+var dateCache = utils.NewCache[time.Time](30*24*time.Hour, 5000)
+
+var VALID_CHANNEL_ID_PATTERN = []*unicode.RangeTable{
+	unicode.Digit,
+}
+
+var PARAMS = []utils.Param{
+	{Name: "channel_id", Description: "Numeric id from ok.ru/video/cid", Example: "1234567", Path: true},
+}
+
+type OkVideo struct {
+	Title     string
+	URL       string
+	Thumbnail string
+	Duration  time.Duration
+}
+
+// parseClock reads durations like 12:34 and 1:02:03.
+func parseClock(s string) time.Duration {
+	var total int
+	for _, part := range strings.Split(strings.TrimSpace(s), ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return time.Duration(total) * time.Second
+}
+
+func absoluteUrl(href string) string {
+	if strings.HasPrefix(href, "//") {
+		return "https:" + href
+	}
+	if strings.HasPrefix(href, "/") {
+		return OKRU_SITE + href
+	}
+	return href
+}
+
+func channelUrl(channelId string) string {
+	return fmt.Sprintf("%s/video/c%s", OKRU_SITE, channelId)
+}
+
+func GetLatestVideosByChannelID(channelId string) (string, []OkVideo, error) {
+	doc, err := utils.FetchDocument(channelUrl(channelId))
+	if err != nil {
+		return "", []OkVideo{}, err
+	}
+
+	title := strings.TrimSpace(doc.Find(CHANNEL_TITLE_SELECTOR).First().Text())
+
+	var videos []OkVideo
+	doc.Find(CARD_SELECTOR).Each(func(_ int, card *goquery.Selection) {
+		link := card.Find(CARD_LINK_SELECTOR).First()
+		href, ok := link.Attr("href")
+		if !ok {
+			return
+		}
+
+		videoTitle := strings.TrimSpace(link.AttrOr("title", ""))
+		if videoTitle == "" {
+			videoTitle = strings.TrimSpace(link.Text())
+		}
+
+		thumbnail, _ := card.Find(CARD_IMAGE_SELECTOR).First().Attr("src")
+
+		videos = append(videos, OkVideo{
+			Title:     videoTitle,
+			URL:       absoluteUrl(strings.SplitN(href, "?", 2)[0]),
+			Thumbnail: absoluteUrl(thumbnail),
+			Duration:  parseClock(card.Find(CARD_DURATION_SELECTOR).First().Text()),
+		})
+	})
+	return title, videos, nil
+}
+
+// GetVideoDate reads the publish date from the page of a video, the
+// channel listing does not show it.
+func GetVideoDate(videoUrl string) (time.Time, error) {
+	if date, ok := dateCache.Get(videoUrl); ok {
+		return date, nil
+	}
+
+	doc, err := utils.FetchDocument(videoUrl)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, selector := range UPLOAD_DATE_SELECTORS {
+		content, ok := doc.Find(selector).First().Attr("content")
+		if !ok {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, strings.TrimSpace(content))
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		dateCache.Set(videoUrl, date)
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("no publish date on %s", videoUrl)
+}
+
+// FeedPath maps ok.ru/video/c<id> to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "ok.ru" || len(parts) < 2 || parts[0] != "video" || !strings.HasPrefix(parts[1], "c") {
+		return "", false
+	}
+
+	id := strings.TrimPrefix(parts[1], "c")
+	channelId := utils.StringsAllowlist(id, VALID_CHANNEL_ID_PATTERN)
+	if channelId == "" || channelId != id {
+		return "", false
+	}
+	return fmt.Sprintf("/okru/%s", channelId), true
+}
+
+func GetFeed(channelId string) (*utils.Feed, error) {
+	title, videos, err := GetLatestVideosByChannelID(channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("no videos")
+	}
+
+	if title == "" {
+		title = channelId
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: fmt.Sprintf("OK @%s", title),
+		Link: &feeds.Link{
+			Href: channelUrl(channelId),
+		},
+		Description: fmt.Sprintf("Лента RSS OK Видео @%s", channelId),
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, entry := range videos {
+		if seenSet.Contains(entry.URL) {
+			continue
+		}
+
+		var description string
+		if entry.Thumbnail != "" {
+			description = fmt.Sprintf(`<p><img src="%s"></p>`, html.EscapeString(entry.Thumbnail))
+		}
+
+		seenSet.Add(entry.URL)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       entry.Title,
+			Link:        &feeds.Link{Href: entry.URL},
+			Description: description,
+			Id:          entry.URL,
+		})
+		feed.SetDuration(entry.URL, entry.Duration)
+	}
+
+	feed.Enrich = func(items []*feeds.Item) {
+		utils.Parallel(len(items), DATE_WORKERS, func(i int) {
+			date, err := GetVideoDate(items[i].Link.Href)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			items[i].Created = date
+		})
+	}
+
+	return feed, nil
+}
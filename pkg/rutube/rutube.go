@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,18 +20,22 @@ import (
 
 const RUTUBE_SITE = "https://rutube.ru"
 
+const SOURCE_NAME = "rutube"
+const SOURCE_TIMEZONE = utils.MOSCOW_TIMEZONE
+
 var VALID_CHANNEL_ID_PATTERN = []*unicode.RangeTable{
 	unicode.Digit,
 }
 
-var VALID_SKIP_BEFORE_PATTERN = []*unicode.RangeTable{
-	unicode.Digit,
+var PARAMS = []utils.Param{
+	{Name: "channel_id", Description: "Numeric id from rutube.ru/channel/id/", Example: "23704195", Path: true},
 }
 
 type RutubeVideo struct {
-	Title string
-	URL   string
-	Date  time.Time
+	Title    string
+	URL      string
+	Date     time.Time
+	Duration time.Duration
 }
 
 type RutubeVideos struct {
@@ -45,6 +50,7 @@ type RutubeJSON struct {
 					VideoURL      string `json:"video_url"`
 					Title         string `json:"title"`
 					PublicationTS string `json:"publication_ts"`
+					Duration      int    `json:"duration"`
 				} `json:"results"`
 			} `json:"data"`
 		} `json:"queries"`
@@ -75,7 +81,7 @@ func stripBetweenTokens(input, startToken, endToken string) string {
 
 func parseTime(input string) (time.Time, error) {
 	layout := "2006-01-02T15:04:05"
-	t, err := time.Parse(layout, input)
+	t, err := time.ParseInLocation(layout, input, utils.SourceLocation(SOURCE_NAME, SOURCE_TIMEZONE))
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -144,7 +150,12 @@ func GetLatestVideosByChannelID(channelId string) (RutubeVideos, error) {
 				log.Println(err)
 				continue
 			}
-			rutubeVideo := RutubeVideo{Title: video.Title, URL: video.VideoURL, Date: date}
+			rutubeVideo := RutubeVideo{
+				Title:    video.Title,
+				URL:      video.VideoURL,
+				Date:     date,
+				Duration: time.Duration(video.Duration) * time.Second,
+			}
 			rutubeVideos.Results = append(rutubeVideos.Results, rutubeVideo)
 		}
 	} else {
@@ -154,30 +165,90 @@ func GetLatestVideosByChannelID(channelId string) (RutubeVideos, error) {
 	return rutubeVideos, nil
 }
 
-func GetFeed(channelId string, skipBefore int) (string, error) {
+// FeedPath maps rutube.ru/channel/<id>/ to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "rutube.ru" || len(parts) < 2 || parts[0] != "channel" {
+		return "", false
+	}
+
+	channelId := utils.StringsAllowlist(parts[1], VALID_CHANNEL_ID_PATTERN)
+	if channelId == "" || channelId != parts[1] {
+		return "", false
+	}
+	return fmt.Sprintf("/rutube/%s", channelId), true
+}
+
+type RutubeVideoJSON struct {
+	Author struct {
+		ID int `json:"id"`
+	} `json:"author"`
+}
+
+// LookupFeedPath finds the channel of a rutube.ru/video/<id>/ or
+// rutube.ru/shorts/<id>/ link through the video API.
+func LookupFeedPath(u *url.URL) (string, bool, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "rutube.ru" || len(parts) < 2 || (parts[0] != "video" && parts[0] != "shorts") {
+		return "", false, nil
+	}
+
+	apiUrl := fmt.Sprintf("%s/api/video/%s/", RUTUBE_SITE, url.PathEscape(parts[1]))
+
+	req, err := http.NewRequest("GET", apiUrl, nil)
+	if err != nil {
+		log.Println(err)
+		return "", true, err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return "", true, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return "", true, err
+	}
+
+	var j RutubeVideoJSON
+	err = json.Unmarshal(body, &j)
+	if err != nil {
+		return "", true, err
+	}
+
+	if j.Author.ID == 0 {
+		return "", true, fmt.Errorf("no author for video %s", parts[1])
+	}
+	return fmt.Sprintf("/rutube/%d", j.Author.ID), true, nil
+}
+
+func GetFeed(channelId string) (*utils.Feed, error) {
 	videos, err := GetLatestVideosByChannelID(channelId)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(videos.Results) == 0 {
-		return "", fmt.Errorf("no videos")
+		return nil, fmt.Errorf("no videos")
 	}
 
-	feed := &feeds.Feed{
+	feed := utils.NewFeed(&feeds.Feed{
 		Title: fmt.Sprintf("Rutube @%s", channelId),
 		Link: &feeds.Link{
 			Href: fmt.Sprintf("https://rutube.ru/channel/%s/", channelId),
 		},
 		Description: fmt.Sprintf("Лента RSS Rutube @%s", channelId),
-	}
+	})
 
 	seenSet := mapset.NewSet[string]()
 	for _, entry := range videos.Results {
-		if skipBefore > int(entry.Date.Unix()) {
-			continue
-		}
-
 		if seenSet.Contains(entry.URL) {
 			continue
 		}
@@ -189,8 +260,8 @@ func GetFeed(channelId string, skipBefore int) (string, error) {
 			Created: entry.Date,
 			Id:      entry.URL,
 		})
-
+		feed.SetDuration(entry.URL, entry.Duration)
 	}
 
-	return feed.ToRss()
+	return feed, nil
 }
@@ -0,0 +1,221 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+const DEFAULT_DATE_LAYOUT = "02.01.2006"
+
+var PARAMS = []utils.Param{
+	{Name: "site", Description: "Site name from the config, its url placeholders are filled from the query string", Example: "example", Path: true},
+}
+
+// Selectors find items on a page, ItemTitle, Link, Date and Description are
+// looked up inside every Item. An empty Link means the item is the link,
+// the link text is the title when ItemTitle is empty or finds nothing.
+type Selectors struct {
+	Item        string `json:"item"`
+	ItemTitle   string `json:"item_title"`
+	Link        string `json:"link"`
+	Date        string `json:"date"`
+	DateLayout  string `json:"date_layout"`
+	Description string `json:"description"`
+}
+
+// Site is a page described in the config and turned into a feed.
+type Site struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Url   string `json:"url"`
+	Selectors
+	Timezone string `json:"timezone"`
+}
+
+type Item struct {
+	Title       string
+	URL         string
+	Date        time.Time
+	Description string
+	// Selection is the matched item, for callers reading more fields
+	Selection *goquery.Selection
+}
+
+func parseDate(s *goquery.Selection, layout string, loc *time.Location) time.Time {
+	if datetime, ok := s.Attr("datetime"); ok {
+		date, err := time.Parse(time.RFC3339, datetime)
+		if err == nil {
+			return date
+		}
+	}
+
+	if layout == "" {
+		layout = DEFAULT_DATE_LAYOUT
+	}
+	text := strings.Join(strings.Fields(s.Text()), " ")
+	date, err := time.ParseInLocation(layout, text, loc)
+	if err != nil {
+		log.Printf("failed to parse date: %s (%v)", text, err)
+	}
+	return date
+}
+
+// ParseItems reads the items of a page, relative links are resolved
+// against base.
+func ParseItems(doc *goquery.Document, base *url.URL, s Selectors, loc *time.Location) []Item {
+	var results []Item
+	doc.Find(s.Item).Each(func(_ int, selection *goquery.Selection) {
+		link := selection
+		if s.Link != "" {
+			link = selection.Find(s.Link).First()
+		}
+		href, ok := link.Attr("href")
+		if !ok {
+			return
+		}
+		ref, ok := utils.ResolveUrl(base, href)
+		if !ok {
+			return
+		}
+
+		// the link text stands in when the title selector finds nothing
+		title := strings.Join(strings.Fields(link.Text()), " ")
+		if s.ItemTitle != "" {
+			if text := strings.Join(strings.Fields(selection.Find(s.ItemTitle).First().Text()), " "); text != "" {
+				title = text
+			}
+		}
+
+		item := Item{
+			Title:     title,
+			URL:       ref,
+			Selection: selection,
+		}
+		if s.Date != "" {
+			item.Date = parseDate(selection.Find(s.Date).First(), s.DateLayout, loc)
+		}
+		if s.Description != "" {
+			raw, err := selection.Find(s.Description).First().Html()
+			if err != nil {
+				log.Println(err)
+			}
+			item.Description, err = utils.SanitizeHtml(raw, base.String())
+			if err != nil {
+				log.Println(err)
+			}
+		}
+
+		results = append(results, item)
+	})
+	return results
+}
+
+// Scrape downloads a page and reads its items.
+func Scrape(pageUrl string, s Selectors, loc *time.Location) ([]Item, error) {
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return []Item{}, err
+	}
+
+	doc, err := utils.FetchDocument(pageUrl)
+	if err != nil {
+		return []Item{}, err
+	}
+
+	return ParseItems(doc, base, s, loc), nil
+}
+
+func (s Site) validate() error {
+	name := utils.StringsAllowlist(s.Name, utils.VALID_NAME_PATTERN)
+	if name == "" || name != s.Name {
+		return fmt.Errorf("invalid site name %q", s.Name)
+	}
+	if !strings.HasPrefix(s.Url, "http://") && !strings.HasPrefix(s.Url, "https://") {
+		return fmt.Errorf("site %s: url is not http(s)", s.Name)
+	}
+	if s.Item == "" {
+		return fmt.Errorf("site %s: empty item selector", s.Name)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("site %s: %v", s.Name, err)
+	}
+	return nil
+}
+
+// Sites checks the sites from the config, invalid ones are logged and
+// skipped.
+func Sites(configs []Site) []Site {
+	var sites []Site
+	for _, site := range configs {
+		if _, ok := utils.FindByName(sites, site.Name); ok {
+			log.Printf("site %s is already defined", site.Name)
+			continue
+		}
+		if site.Timezone == "" {
+			site.Timezone = utils.MOSCOW_TIMEZONE
+		}
+		if err := site.validate(); err != nil {
+			log.Println(err)
+			continue
+		}
+		if site.Title == "" {
+			site.Title = site.Name
+		}
+		sites = append(sites, site)
+	}
+	return sites
+}
+
+func (s Site) GetName() string {
+	return s.Name
+}
+
+func GetFeed(site Site, vars url.Values) (*utils.Feed, error) {
+	pageUrl, err := utils.FillTemplate(site.Url, vars, url.PathEscape)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := Scrape(pageUrl, site.Selectors, utils.SourceLocation(site.Name, site.Timezone))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no items on %s", pageUrl)
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: site.Title,
+		Link: &feeds.Link{
+			Href: pageUrl,
+		},
+		Description: fmt.Sprintf("Лента RSS %s", site.Title),
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, entry := range results {
+		if seenSet.Contains(entry.URL) {
+			continue
+		}
+
+		seenSet.Add(entry.URL)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       entry.Title,
+			Link:        &feeds.Link{Href: entry.URL},
+			Description: entry.Description,
+			Created:     entry.Date,
+			Id:          entry.URL,
+		})
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const testPage = `<html><body>
+<article class="news">
+	<h2>First   news</h2>
+	<a class="news__link" href="/news/1">more</a>
+	<span class="date">02.01.2025 15:04</span>
+	<div class="lead"><p onclick="x()">Lead <script>alert(1)</script>text</p></div>
+</article>
+<article class="news">
+	<a class="news__link" href="https://other.example/news/2">Second news</a>
+	<time datetime="2025-01-03T10:00:00Z">3 января</time>
+</article>
+<article class="news">
+	<a class="news__link" href="javascript:alert(1)">Script link</a>
+</article>
+<article class="news">
+	<span>No link</span>
+</article>
+</body></html>`
+
+func TestParseItems(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.ru/news/")
+	loc := time.FixedZone("MSK", 3*60*60)
+	selectors := Selectors{
+		Item:        "article.news",
+		ItemTitle:   "h2",
+		Link:        "a.news__link",
+		Date:        ".date, time",
+		DateLayout:  "02.01.2006 15:04",
+		Description: ".lead",
+	}
+
+	items := ParseItems(doc, base, selectors, loc)
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+
+	tests := []struct {
+		got, want string
+	}{
+		{items[0].Title, "First news"},
+		{items[0].URL, "https://example.ru/news/1"},
+		{items[0].Date.Format(time.RFC3339), "2025-01-02T15:04:00+03:00"},
+		{items[0].Description, "<p>Lead text</p>"},
+		// the title selector finds nothing in the second item
+		{items[1].Title, "Second news"},
+		{items[1].URL, "https://other.example/news/2"},
+		{items[1].Date.Format(time.RFC3339), "2025-01-03T10:00:00Z"},
+	}
+	for i, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("case %d: got %q, want %q", i, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseItemsLinkIsItem(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<ul><li><a class="item" href="a.html"> Item  one </a></li></ul>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.ru/list/")
+
+	items := ParseItems(doc, base, Selectors{Item: "a.item"}, time.UTC)
+	if len(items) != 1 || items[0].Title != "Item one" || items[0].URL != "https://example.ru/list/a.html" {
+		t.Errorf("got %+v", items)
+	}
+}
@@ -0,0 +1,203 @@
+package smotrim
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// https://api.smotrim.ru/api/v1/videos?brands=62358&limit=20
+
+const SMOTRIM_API = "https://api.smotrim.ru/api/v1"
+const SMOTRIM_SITE = "https://smotrim.ru"
+
+const SOURCE_NAME = "smotrim"
+const SOURCE_TIMEZONE = utils.MOSCOW_TIMEZONE
+
+const VIDEOS_COUNT = "20"
+
+// preferred thumbnail sizes, the first one found wins
+var THUMBNAIL_PRESETS = []string{"hd", "lw", "bq"}
+
+var VALID_BRAND_ID_PATTERN = []*unicode.RangeTable{
+	unicode.Digit,
+}
+
+var PARAMS = []utils.Param{
+	{Name: "brand_id", Description: "Programme id from smotrim.ru/brand/id", Example: "62358", Path: true},
+}
+
+type SmotrimVideoJSON struct {
+	ID           int    `json:"id"`
+	Title        string `json:"title"`
+	EpisodeTitle string `json:"episodeTitle"`
+	BrandTitle   string `json:"brandTitle"`
+	Anons        string `json:"anons"`
+	Duration     int    `json:"duration"`
+	DatePub      string `json:"datePub"`
+	Pictures     struct {
+		Sizes []struct {
+			Preset string `json:"preset"`
+			URL    string `json:"url"`
+		} `json:"sizes"`
+	} `json:"pictures"`
+}
+
+type SmotrimVideosJSON struct {
+	Data []SmotrimVideoJSON `json:"data"`
+}
+
+func parseTime(input string) (time.Time, error) {
+	layout := "02-01-2006 15:04:05"
+	return time.ParseInLocation(layout, input, utils.SourceLocation(SOURCE_NAME, SOURCE_TIMEZONE))
+}
+
+func GetLatestVideosByBrandID(brandId string) (SmotrimVideosJSON, error) {
+	apiUrl := fmt.Sprintf("%s/videos", SMOTRIM_API)
+	params := map[string]string{
+		"brands": brandId,
+		"limit":  VIDEOS_COUNT,
+	}
+
+	req, err := http.NewRequest("GET", apiUrl, nil)
+	if err != nil {
+		log.Println(err)
+		return SmotrimVideosJSON{}, err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+
+	q := req.URL.Query()
+	for key, value := range params {
+		q.Add(key, value)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return SmotrimVideosJSON{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return SmotrimVideosJSON{}, fmt.Errorf("got status %d for brand %s", resp.StatusCode, brandId)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return SmotrimVideosJSON{}, err
+	}
+
+	var j SmotrimVideosJSON
+	err = json.Unmarshal(body, &j)
+	if err != nil {
+		return SmotrimVideosJSON{}, err
+	}
+
+	return j, nil
+}
+
+// FeedPath maps smotrim.ru/brand/<id> to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "smotrim.ru" || len(parts) < 2 || parts[0] != "brand" {
+		return "", false
+	}
+
+	brandId := utils.StringsAllowlist(parts[1], VALID_BRAND_ID_PATTERN)
+	if brandId == "" || brandId != parts[1] {
+		return "", false
+	}
+	return fmt.Sprintf("/smotrim/%s", brandId), true
+}
+
+func thumbnail(video SmotrimVideoJSON) string {
+	for _, preset := range THUMBNAIL_PRESETS {
+		for _, size := range video.Pictures.Sizes {
+			if size.Preset == preset {
+				return size.URL
+			}
+		}
+	}
+	if len(video.Pictures.Sizes) > 0 {
+		return video.Pictures.Sizes[0].URL
+	}
+	return ""
+}
+
+func videoTitle(video SmotrimVideoJSON) string {
+	if video.EpisodeTitle != "" && video.EpisodeTitle != video.Title {
+		return fmt.Sprintf("%s. %s", video.Title, video.EpisodeTitle)
+	}
+	return video.Title
+}
+
+func GetFeed(brandId string) (*utils.Feed, error) {
+	videos, err := GetLatestVideosByBrandID(brandId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(videos.Data) == 0 {
+		return nil, fmt.Errorf("no videos")
+	}
+
+	title := videos.Data[0].BrandTitle
+	if title == "" {
+		title = brandId
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: fmt.Sprintf("Smotrim @%s", title),
+		Link: &feeds.Link{
+			Href: fmt.Sprintf("%s/brand/%s", SMOTRIM_SITE, brandId),
+		},
+		Description: fmt.Sprintf("Лента RSS Smotrim @%s", brandId),
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, entry := range videos.Data {
+		videoUrl := fmt.Sprintf("%s/video/%d", SMOTRIM_SITE, entry.ID)
+
+		if seenSet.Contains(videoUrl) {
+			continue
+		}
+
+		date, err := parseTime(entry.DatePub)
+		if err != nil {
+			log.Println(err)
+		}
+
+		var description string
+		if src := thumbnail(entry); src != "" {
+			description = fmt.Sprintf(`<p><img src="%s"></p>`, html.EscapeString(src))
+		}
+		description += utils.TextToHtml(utils.HtmlToText(entry.Anons), nil)
+
+		seenSet.Add(videoUrl)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       videoTitle(entry),
+			Link:        &feeds.Link{Href: videoUrl},
+			Description: description,
+			Created:     date,
+			Id:          videoUrl,
+		})
+		feed.SetDuration(videoUrl, time.Duration(entry.Duration)*time.Second)
+	}
+
+	return feed, nil
+}
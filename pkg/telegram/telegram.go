@@ -0,0 +1,290 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// https://t.me/s/durov
+
+const TELEGRAM_SITE = "https://t.me"
+
+var VALID_CHANNEL_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	{R16: []unicode.Range16{{'_', '_', 1}}},
+}
+
+var PARAMS = []utils.Param{
+	{Name: "channel", Description: "Public channel name from t.me/name", Example: "durov", Path: true},
+}
+
+var backgroundUrlRe = regexp.MustCompile(`background-image:\s*url\(['"]?([^'")]+)['"]?\)`)
+
+type Message struct {
+	ID            string
+	URL           string
+	Date          time.Time
+	TextHtml      string
+	Text          string
+	Photos        []string
+	Videos        []string
+	ForwardedFrom string
+	ForwardedUrl  string
+	Views         string
+}
+
+type Channel struct {
+	Title       string
+	Description string
+	Messages    []Message
+}
+
+func backgroundUrl(s *goquery.Selection) string {
+	style, _ := s.Attr("style")
+	match := backgroundUrlRe.FindStringSubmatch(style)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func parseMessage(s *goquery.Selection) (Message, bool) {
+	id, ok := s.Attr("data-post")
+	if !ok {
+		return Message{}, false
+	}
+
+	message := Message{ID: id}
+
+	dateLink := s.Find("a.tgme_widget_message_date").First()
+	message.URL, _ = dateLink.Attr("href")
+	if message.URL == "" {
+		message.URL = fmt.Sprintf("%s/%s", TELEGRAM_SITE, id)
+	}
+
+	if datetime, ok := dateLink.Find("time").Attr("datetime"); ok {
+		date, err := time.Parse(time.RFC3339, datetime)
+		if err != nil {
+			log.Println(err)
+		}
+		message.Date = date
+	}
+
+	text := s.Find(".tgme_widget_message_text").First()
+	if text.Length() > 0 {
+		raw, err := text.Html()
+		if err != nil {
+			log.Println(err)
+		}
+		message.TextHtml = raw
+		message.Text = utils.HtmlToText(raw)
+	}
+
+	s.Find("a.tgme_widget_message_photo_wrap").Each(func(_ int, photo *goquery.Selection) {
+		if src := backgroundUrl(photo); src != "" {
+			message.Photos = append(message.Photos, src)
+		}
+	})
+
+	s.Find("video.tgme_widget_message_video").Each(func(_ int, video *goquery.Selection) {
+		if src, ok := video.Attr("src"); ok && src != "" {
+			message.Videos = append(message.Videos, src)
+		}
+	})
+	// videos too large for the preview only have a thumbnail
+	s.Find(".tgme_widget_message_video_thumb").Each(func(_ int, thumb *goquery.Selection) {
+		if src := backgroundUrl(thumb); src != "" {
+			message.Photos = append(message.Photos, src)
+		}
+	})
+
+	forwarded := s.Find(".tgme_widget_message_forwarded_from_name").First()
+	if forwarded.Length() > 0 {
+		message.ForwardedFrom = strings.TrimSpace(forwarded.Text())
+		message.ForwardedUrl, _ = forwarded.Attr("href")
+	}
+
+	message.Views = strings.TrimSpace(s.Find(".tgme_widget_message_views").First().Text())
+
+	return message, true
+}
+
+func GetLatestMessagesByChannel(channel string) (Channel, error) {
+	url := fmt.Sprintf("%s/s/%s", TELEGRAM_SITE, channel)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		log.Println(err)
+		return Channel{}, err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return Channel{}, err
+	}
+	defer resp.Body.Close()
+
+	// private and missing channels redirect to the plain t.me page
+	if !strings.EqualFold(resp.Request.URL.Path, fmt.Sprintf("/s/%s", channel)) {
+		return Channel{}, fmt.Errorf("channel %s has no public preview", channel)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return Channel{}, err
+	}
+
+	result := Channel{
+		Title:       strings.TrimSpace(doc.Find(".tgme_channel_info_header_title").First().Text()),
+		Description: strings.TrimSpace(doc.Find(".tgme_channel_info_description").First().Text()),
+	}
+
+	doc.Find(".tgme_widget_message").Each(func(_ int, s *goquery.Selection) {
+		if message, ok := parseMessage(s); ok {
+			result.Messages = append(result.Messages, message)
+		}
+	})
+
+	return result, nil
+}
+
+// FeedPath maps t.me/name, t.me/s/name and t.me/name/123 to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host != "t.me" && u.Host != "telegram.me" {
+		return "", false
+	}
+	if parts[0] == "s" && len(parts) > 1 {
+		parts = parts[1:]
+	}
+
+	channel := utils.StringsAllowlist(parts[0], VALID_CHANNEL_PATTERN)
+	if channel == "" || channel != parts[0] || channel == "joinchat" {
+		return "", false
+	}
+	return fmt.Sprintf("/telegram/%s", channel), true
+}
+
+func messageTitle(message Message) string {
+	for _, line := range strings.Split(message.Text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return utils.Truncate(line, 100)
+		}
+	}
+	if len(message.Videos) > 0 {
+		return "Видео"
+	}
+	if len(message.Photos) > 0 {
+		return "Фото"
+	}
+	return message.ID
+}
+
+func messageDescription(message Message) string {
+	var b strings.Builder
+
+	if message.ForwardedFrom != "" {
+		if message.ForwardedUrl != "" {
+			fmt.Fprintf(&b, `<p>Переслано из <a href="%s">%s</a></p>`,
+				html.EscapeString(message.ForwardedUrl), html.EscapeString(message.ForwardedFrom))
+		} else {
+			fmt.Fprintf(&b, "<p>Переслано из %s</p>", html.EscapeString(message.ForwardedFrom))
+		}
+	}
+
+	if message.TextHtml != "" {
+		text, err := utils.SanitizeHtml(message.TextHtml, message.URL)
+		if err != nil {
+			log.Println(err)
+			text = utils.TextToHtml(message.Text, nil)
+		}
+		fmt.Fprintf(&b, "<p>%s</p>", text)
+	}
+
+	for _, photo := range message.Photos {
+		fmt.Fprintf(&b, `<p><img src="%s"></p>`, html.EscapeString(photo))
+	}
+	for _, video := range message.Videos {
+		fmt.Fprintf(&b, `<p><a href="%s">Видео</a></p>`, html.EscapeString(video))
+	}
+
+	if message.Views != "" {
+		fmt.Fprintf(&b, "<p>Просмотры: %s</p>", html.EscapeString(message.Views))
+	}
+
+	return b.String()
+}
+
+func messageEnclosure(message Message) *feeds.Enclosure {
+	switch {
+	case len(message.Videos) > 0:
+		return &feeds.Enclosure{Url: message.Videos[0], Length: "0", Type: "video/mp4"}
+	case len(message.Photos) > 0:
+		return &feeds.Enclosure{Url: message.Photos[0], Length: "0", Type: "image/jpeg"}
+	}
+	return nil
+}
+
+func GetFeed(channel string) (*utils.Feed, error) {
+	result, err := GetLatestMessagesByChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Messages) == 0 {
+		return nil, fmt.Errorf("no messages")
+	}
+
+	title := result.Title
+	if title == "" {
+		title = channel
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: fmt.Sprintf("Telegram @%s", title),
+		Link: &feeds.Link{
+			Href: fmt.Sprintf("%s/s/%s", TELEGRAM_SITE, channel),
+		},
+		Description: fmt.Sprintf("Лента RSS Telegram @%s", channel),
+	})
+
+	// the preview lists messages oldest first
+	seenSet := mapset.NewSet[string]()
+	for i := len(result.Messages) - 1; i >= 0; i-- {
+		message := result.Messages[i]
+
+		if seenSet.Contains(message.URL) {
+			continue
+		}
+
+		seenSet.Add(message.URL)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       messageTitle(message),
+			Link:        &feeds.Link{Href: message.URL},
+			Description: messageDescription(message),
+			Created:     message.Date,
+			Id:          message.URL,
+			Enclosure:   messageEnclosure(message),
+		})
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+type cacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// Cache is a small in-memory cache safe for concurrent use. Entries expire
+// after ttl and the oldest entry is evicted once maxEntries is reached.
+type Cache[V any] struct {
+	mu         sync.Mutex
+	entries    map[string]cacheEntry[V]
+	order      []string
+	ttl        time.Duration
+	maxEntries int
+}
+
+func NewCache[V any](ttl time.Duration, maxEntries int) *Cache[V] {
+	return &Cache[V]{
+		entries:    make(map[string]cacheEntry[V]),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *Cache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = cacheEntry[V]{value: value, expires: time.Now().Add(c.ttl)}
+
+	for len(c.order) > c.maxEntries {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
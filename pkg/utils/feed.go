@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// Feed is a feeds.Feed plus the per-item data gorilla/feeds has no field
+// for, keyed by item Id.
+type Feed struct {
+	*feeds.Feed
+	Categories map[string]string
+	Durations  map[string]time.Duration
+	// Stylesheet is an XSL stylesheet URL referenced from the RSS output
+	// so browsers render the feed as a page.
+	Stylesheet string
+	// Enrich does the expensive per-item work, like fetching full texts,
+	// and runs only on the items left after filtering.
+	Enrich func(items []*feeds.Item)
+	// Podcast adds the iTunes tags podcast apps expect, durations of items
+	// become itunes:duration.
+	Podcast bool
+}
+
+const ITUNES_NAMESPACE = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+type itunesImage struct {
+	XMLName xml.Name `xml:"itunes:image"`
+	Href    string   `xml:"href,attr"`
+}
+
+type podcastItem struct {
+	*feeds.RssItem
+	ItunesDuration string `xml:"itunes:duration,omitempty"`
+}
+
+type podcastChannel struct {
+	*feeds.RssFeed
+	ItunesAuthor   string `xml:"itunes:author,omitempty"`
+	ItunesImage    *itunesImage
+	ItunesExplicit string         `xml:"itunes:explicit"`
+	Items          []*podcastItem `xml:"item"`
+}
+
+type podcastRssXml struct {
+	XMLName          xml.Name `xml:"rss"`
+	Version          string   `xml:"version,attr"`
+	ContentNamespace string   `xml:"xmlns:content,attr"`
+	ItunesNamespace  string   `xml:"xmlns:itunes,attr"`
+	Channel          *podcastChannel
+}
+
+func (c *podcastChannel) FeedXml() interface{} {
+	return &podcastRssXml{
+		Version:          "2.0",
+		ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+		ItunesNamespace:  ITUNES_NAMESPACE,
+		Channel:          c,
+	}
+}
+
+func formatItunesDuration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+func (f *Feed) podcastChannel(rss *feeds.RssFeed) *podcastChannel {
+	channel := &podcastChannel{
+		RssFeed:        rss,
+		ItunesExplicit: "false",
+	}
+	if f.Author != nil {
+		channel.ItunesAuthor = f.Author.Name
+	}
+	if f.Image != nil && f.Image.Url != "" {
+		channel.ItunesImage = &itunesImage{Href: f.Image.Url}
+	}
+	for i, item := range f.Items {
+		episode := &podcastItem{RssItem: rss.Items[i]}
+		if duration := f.Durations[item.Id]; duration > 0 {
+			episode.ItunesDuration = formatItunesDuration(duration)
+		}
+		channel.Items = append(channel.Items, episode)
+	}
+	return channel
+}
+
+func NewFeed(feed *feeds.Feed) *Feed {
+	return &Feed{
+		Feed:       feed,
+		Categories: map[string]string{},
+		Durations:  map[string]time.Duration{},
+	}
+}
+
+func (f *Feed) SetCategory(id, category string) {
+	f.Categories[id] = category
+}
+
+func (f *Feed) SetDuration(id string, duration time.Duration) {
+	f.Durations[id] = duration
+}
+
+func sanitizeDescription(item *feeds.Item) string {
+	var baseUrl string
+	if item.Link != nil {
+		baseUrl = item.Link.Href
+	}
+	description, err := SanitizeHtml(item.Description, baseUrl)
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
+	return description
+}
+
+func (f *Feed) ToRss() (string, error) {
+	rss := (&feeds.Rss{Feed: f.Feed}).RssFeed()
+	for i, item := range f.Items {
+		rss.Items[i].Category = f.Categories[item.Id]
+		// the stylesheet renders descriptions as HTML on the bridge's origin
+		if f.Stylesheet != "" {
+			rss.Items[i].Description = sanitizeDescription(item)
+		}
+	}
+	var out string
+	var err error
+	if f.Podcast {
+		out, err = feeds.ToXML(f.podcastChannel(rss))
+	} else {
+		out, err = feeds.ToXML(rss)
+	}
+	if err != nil || f.Stylesheet == "" {
+		return out, err
+	}
+
+	header := xml.Header[:len(xml.Header)-1]
+	stylesheet := fmt.Sprintf(`<?xml-stylesheet type="text/xsl" href="%s"?>`, f.Stylesheet)
+	return strings.Replace(out, header, header+"\n"+stylesheet+"\n", 1), nil
+}
@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var urlRe = regexp.MustCompile(`https?://[^\s<>"]+`)
+var paragraphSplitRe = regexp.MustCompile(`\n\s*\n`)
+
+// TrimUrl drops trailing punctuation, which is almost always part of the
+// sentence rather than the link.
+func TrimUrl(url string) string {
+	return strings.TrimRight(url, ".,;:!?)»")
+}
+
+// LinkifyLine escapes a line of plain text and turns URLs into links.
+func LinkifyLine(line string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range urlRe.FindAllStringIndex(line, -1) {
+		url := TrimUrl(line[loc[0]:loc[1]])
+		b.WriteString(html.EscapeString(line[last:loc[0]]))
+		fmt.Fprintf(&b, `<a href="%s">%s</a>`, html.EscapeString(url), html.EscapeString(url))
+		last = loc[0] + len(url)
+	}
+	b.WriteString(html.EscapeString(line[last:]))
+	return b.String()
+}
+
+// TextToHtml renders plain text as HTML paragraphs split on blank lines,
+// keeping single line breaks. renderLine must escape its input, nil means
+// LinkifyLine.
+func TextToHtml(text string, renderLine func(string) string) string {
+	if renderLine == nil {
+		renderLine = LinkifyLine
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var b strings.Builder
+	for _, paragraph := range paragraphSplitRe.Split(text, -1) {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		lines := strings.Split(paragraph, "\n")
+		for i, line := range lines {
+			lines[i] = renderLine(strings.TrimSpace(line))
+		}
+		fmt.Fprintf(&b, "<p>%s</p>", strings.Join(lines, "<br>"))
+	}
+	return b.String()
+}
+
+// HtmlToText drops markup and returns the text content of an HTML fragment.
+func HtmlToText(fragment string) string {
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(fragment))
+	var b strings.Builder
+	for {
+		switch tokenizer.Next() {
+		case xhtml.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case xhtml.TextToken:
+			b.Write(tokenizer.Text())
+		case xhtml.StartTagToken, xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			switch atom.Lookup(name) {
+			case atom.P, atom.Br, atom.Div, atom.Li, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				b.WriteString("\n")
+			}
+		}
+	}
+}
+
+// Truncate shortens text to at most n runes on a word boundary.
+func Truncate(text string, n int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	cut := string(runes[:n])
+	if idx := strings.LastIndexAny(cut, " \n\t"); idx > 0 {
+		cut = cut[:idx]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
+
+var allowedTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.A: true, atom.Img: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true,
+	atom.Pre: true, atom.Code: true, atom.Em: true, atom.Strong: true,
+	atom.B: true, atom.I: true, atom.U: true, atom.S: true, atom.Sub: true, atom.Sup: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Hr: true,
+	atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tr: true, atom.Th: true, atom.Td: true,
+}
+
+// dropped together with their contents, everything else unknown is unwrapped
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Noscript: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Svg: true,
+	atom.Object: true, atom.Embed: true, atom.Template: true,
+}
+
+var allowedAttrs = map[string]bool{
+	"href": true, "src": true, "alt": true, "title": true,
+}
+
+// ResolveUrl resolves ref against base, links other than http(s) are
+// rejected.
+func ResolveUrl(base *url.URL, ref string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func sanitizeNode(b *strings.Builder, n *xhtml.Node, base *url.URL) {
+	switch n.Type {
+	case xhtml.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case xhtml.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			sanitizeNode(b, c, base)
+		}
+		return
+	}
+
+	if droppedTags[n.DataAtom] {
+		return
+	}
+
+	if !allowedTags[n.DataAtom] {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			sanitizeNode(b, c, base)
+		}
+		return
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range n.Attr {
+		key := strings.ToLower(attr.Key)
+		// lazy loaded images keep the real source in data-src
+		if key == "data-src" && n.DataAtom == atom.Img {
+			key = "src"
+		}
+		if !allowedAttrs[key] {
+			continue
+		}
+		if key == "href" || key == "src" {
+			resolved, ok := ResolveUrl(base, attr.Val)
+			if !ok {
+				continue
+			}
+			attr.Val = resolved
+		}
+		attrs[key] = attr.Val
+	}
+
+	if n.DataAtom == atom.Img && attrs["src"] == "" {
+		return
+	}
+
+	b.WriteString("<" + n.Data)
+	for _, key := range []string{"href", "src", "alt", "title"} {
+		if val, ok := attrs[key]; ok {
+			fmt.Fprintf(b, ` %s="%s"`, key, html.EscapeString(val))
+		}
+	}
+	b.WriteString(">")
+
+	if n.DataAtom == atom.Img || n.DataAtom == atom.Br || n.DataAtom == atom.Hr {
+		return
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(b, c, base)
+	}
+	b.WriteString("</" + n.Data + ">")
+}
+
+// SanitizeHtml keeps a small allowlist of formatting tags and attributes,
+// resolving relative links against baseUrl and dropping non-http(s) ones.
+func SanitizeHtml(fragment, baseUrl string) (string, error) {
+	base, err := url.Parse(baseUrl)
+	if err != nil {
+		return "", err
+	}
+
+	context := &xhtml.Node{Type: xhtml.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := xhtml.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		sanitizeNode(&b, n, base)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestSanitizeHtml(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text is escaped", `a < b & c`, `a &lt; b &amp; c`},
+		{"allowed tags are kept", `<p><strong>bold</strong></p>`, `<p><strong>bold</strong></p>`},
+		{"unknown tags are unwrapped", `<div><span>text</span></div>`, `text`},
+		{"scripts are dropped", `<p>a</p><script>alert(1)</script>`, `<p>a</p>`},
+		{"styles are dropped", `<style>p{}</style>b`, `b`},
+		{"event handlers are dropped", `<p onclick="alert(1)" class="x">a</p>`, `<p>a</p>`},
+		{"relative links are resolved", `<a href="/post/1">post</a>`, `<a href="https://example.com/post/1">post</a>`},
+		{"javascript links are dropped", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"data images are dropped", `<img src="data:image/png;base64,AAAA">`, ``},
+		{"lazy images use data-src", `<img data-src="img.png" alt="pic">`, `<img src="https://example.com/blog/img.png" alt="pic">`},
+		{"attribute values are escaped", `<img src="a.png" alt="&quot;><script>">`, `<img src="https://example.com/blog/a.png" alt="&#34;&gt;&lt;script&gt;">`},
+	}
+
+	for _, tt := range tests {
+		got, err := SanitizeHtml(tt.input, "https://example.com/blog/")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: SanitizeHtml(%q) = %q, want %q", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
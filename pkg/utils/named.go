@@ -0,0 +1,27 @@
+package utils
+
+import "unicode"
+
+// VALID_NAME_PATTERN checks the names of sites, APIs and managers from the
+// config and the query string values filled into their templates.
+var VALID_NAME_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	{R16: []unicode.Range16{{'_', '_', 1}}},
+	{R16: []unicode.Range16{{'-', '-', 1}}},
+}
+
+// Named is an entry of the config looked up by its name in the route.
+type Named interface {
+	GetName() string
+}
+
+func FindByName[T Named](items []T, name string) (T, bool) {
+	for _, item := range items {
+		if item.GetName() == name {
+			return item, true
+		}
+	}
+	var zero T
+	return zero, false
+}
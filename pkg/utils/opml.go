@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"encoding/xml"
+)
+
+type OpmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XmlUrl   string        `xml:"xmlUrl,attr,omitempty"`
+	HtmlUrl  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []OpmlOutline `xml:"outline,omitempty"`
+}
+
+type Opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title string `xml:"title"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []OpmlOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+func NewOpml(title string, outlines []OpmlOutline) *Opml {
+	opml := &Opml{Version: "2.0"}
+	opml.Head.Title = title
+	opml.Body.Outlines = outlines
+	return opml
+}
+
+// NewRssOutline describes a single RSS subscription.
+func NewRssOutline(title, xmlUrl, htmlUrl string) OpmlOutline {
+	return OpmlOutline{
+		Text:    title,
+		Title:   title,
+		Type:    "rss",
+		XmlUrl:  xmlUrl,
+		HtmlUrl: htmlUrl,
+	}
+}
+
+func (o *Opml) ToXML() (string, error) {
+	data, err := xml.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data), nil
+}
+
+func ParseOpml(data []byte) (*Opml, error) {
+	var opml Opml
+	err := xml.Unmarshal(data, &opml)
+	if err != nil {
+		return nil, err
+	}
+	return &opml, nil
+}
+
+func collectUrls(outlines []OpmlOutline, urls []string) []string {
+	for _, outline := range outlines {
+		switch {
+		case outline.HtmlUrl != "":
+			urls = append(urls, outline.HtmlUrl)
+		case outline.XmlUrl != "":
+			urls = append(urls, outline.XmlUrl)
+		}
+		urls = collectUrls(outline.Outlines, urls)
+	}
+	return urls
+}
+
+// Urls lists the site links of every outline, falling back to feed links.
+func (o *Opml) Urls() []string {
+	return collectUrls(o.Body.Outlines, nil)
+}
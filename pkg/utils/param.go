@@ -0,0 +1,10 @@
+package utils
+
+// Param documents a parameter of a feed route for the web UI.
+type Param struct {
+	Name        string
+	Description string
+	Example     string
+	Path        bool     // part of the route rather than the query string
+	Options     []string // allowed values, empty means free text
+}
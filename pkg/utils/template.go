@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// {name} placeholders in config templates, filled from the query string
+var placeholderRe = regexp.MustCompile(`\{(\w+)\}`)
+
+// FillTemplate replaces {name} placeholders with query string values passed
+// through escape, e.g. url.PathEscape for URLs or JsonEscape for bodies.
+// Values outside VALID_NAME_PATTERN are rejected rather than rewritten.
+func FillTemplate(template string, vars url.Values, escape func(string) string) (string, error) {
+	var missing, invalid []string
+	filled := placeholderRe.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value := strings.TrimSpace(vars.Get(name))
+		if value == "" {
+			missing = append(missing, name)
+		} else if StringsAllowlist(value, VALID_NAME_PATTERN) != value {
+			invalid = append(invalid, name)
+		}
+		return escape(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	if len(invalid) > 0 {
+		return "", fmt.Errorf("invalid %s", strings.Join(invalid, ", "))
+	}
+	return filled, nil
+}
+
+// JsonEscape escapes s for use inside a JSON string literal.
+func JsonEscape(s string) string {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	return string(raw[1 : len(raw)-1])
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFillTemplate(t *testing.T) {
+	vars := url.Values{
+		"section": {"city"},
+		"channel": {`news"}, "x": {"`},
+		"spaced":  {"a b"},
+		"trimmed": {"  town  "},
+	}
+
+	tests := []struct {
+		template string
+		escape   func(string) string
+		want     string
+		wantErr  bool
+	}{
+		{"https://example.ru/{section}/", url.PathEscape, "https://example.ru/city/", false},
+		{"https://example.ru/{section}?q={missing}", url.PathEscape, "", true},
+		{"https://example.ru/{trimmed}", url.PathEscape, "https://example.ru/town", false},
+		// values outside the allowlist are rejected, not rewritten
+		{"https://example.ru/{spaced}", url.PathEscape, "", true},
+		{`{"channel": "{channel}"}`, JsonEscape, "", true},
+		{`{"section": "{section}"}`, JsonEscape, `{"section": "city"}`, false},
+		{"no placeholders", JsonEscape, "no placeholders", false},
+	}
+
+	for _, tt := range tests {
+		got, err := FillTemplate(tt.template, vars, tt.escape)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FillTemplate(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("FillTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestJsonEscape(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"plain", "plain"},
+		{`a"b`, `a\"b`},
+		{`back\slash`, `back\\slash`},
+		{"line\nbreak", `line\nbreak`},
+		{"<tag>", `\u003ctag\u003e`},
+	}
+
+	for _, tt := range tests {
+		if got := JsonEscape(tt.input); got != tt.want {
+			t.Errorf("JsonEscape(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SOURCE_TIMEZONES_ENV overrides the timezone a bridge assumes for its
+// source, e.g. SOURCE_TIMEZONES="rutube=UTC,accent-am=Asia/Yekaterinburg".
+const SOURCE_TIMEZONES_ENV = "SOURCE_TIMEZONES"
+
+const MOSCOW_TIMEZONE = "Europe/Moscow"
+
+var timezoneOverrides map[string]string
+var timezoneOverridesOnce sync.Once
+
+func loadTimezoneOverrides() {
+	timezoneOverrides = map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(SOURCE_TIMEZONES_ENV), ",") {
+		source, zone, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		timezoneOverrides[strings.TrimSpace(source)] = strings.TrimSpace(zone)
+	}
+}
+
+// SourceLocation returns the timezone of a bridge's source, honouring
+// overrides from SOURCE_TIMEZONES_ENV.
+func SourceLocation(source, defaultZone string) *time.Location {
+	timezoneOverridesOnce.Do(loadTimezoneOverrides)
+
+	zone := defaultZone
+	if override, ok := timezoneOverrides[source]; ok {
+		zone = override
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		log.Printf("Unknown timezone %s for %s, falling back to UTC\n", zone, source)
+		return time.UTC
+	}
+	return loc
+}
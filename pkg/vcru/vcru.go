@@ -0,0 +1,97 @@
+package vcru
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/articles"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// https://vc.ru/marketing
+// https://vc.ru/u/1389185-ivan-petrov
+
+const VCRU_SITE = "https://vc.ru"
+
+const (
+	KIND_SECTION = "section"
+	KIND_AUTHOR  = "author"
+)
+
+var VALID_NAME_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	{R16: []unicode.Range16{{'_', '_', 1}}},
+	{R16: []unicode.Range16{{'-', '-', 1}}},
+}
+
+// first path segments on vc.ru that are not sections
+var RESERVED_PATHS = []string{"u", "s", "tag", "search", "new", "popular", "discovery", "editor", "auth", "api", "rss"}
+
+var SECTION_PARAMS = []utils.Param{
+	{Name: "name", Description: "Section name from vc.ru/name", Example: "marketing", Path: true},
+}
+
+var AUTHOR_PARAMS = []utils.Param{
+	{Name: "name", Description: "Author id and name from vc.ru/u/name", Example: "1389185-ivan-petrov", Path: true},
+}
+
+// listing and article markup, kept together since the site changes it often
+var SITE = articles.Site{
+	BaseUrl:   VCRU_SITE,
+	Item:      ".content-feed",
+	Title:     ".content-title",
+	Link:      "a.content__link",
+	Author:    ".content-header__author .author__name",
+	Date:      "time[datetime]",
+	Lead:      ".content__body",
+	Tags:      ".content-header__item a[href*='/tag/'], .content-header__topic",
+	TagsLabel: "Теги",
+	Rating:    ".like-button__count, .reaction-button__count",
+	Comments:  ".comments-counter__count",
+	Body:      []string{".content--full .content__body, article .content__body"},
+}
+
+func listUrl(kind, name string) string {
+	if kind == KIND_AUTHOR {
+		return fmt.Sprintf("%s/u/%s", VCRU_SITE, name)
+	}
+	return fmt.Sprintf("%s/%s", VCRU_SITE, name)
+}
+
+// FeedPath maps vc.ru section and author pages to the bridge routes.
+func FeedPath(u *url.URL) (string, bool) {
+	if u.Host != "vc.ru" {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	if parts[0] == "u" && len(parts) >= 2 {
+		name := utils.StringsAllowlist(parts[1], VALID_NAME_PATTERN)
+		if name == "" || name != parts[1] {
+			return "", false
+		}
+		return fmt.Sprintf("/vcru/u/%s", name), true
+	}
+
+	// vc.ru/<section>/<id>-<slug> is an article within the section
+	if parts[0] == "" || slices.Contains(RESERVED_PATHS, parts[0]) {
+		return "", false
+	}
+	name := utils.StringsAllowlist(parts[0], VALID_NAME_PATTERN)
+	if name == "" || name != parts[0] {
+		return "", false
+	}
+	return fmt.Sprintf("/vcru/%s", name), true
+}
+
+func GetFeed(kind, name string) (*utils.Feed, error) {
+	title := fmt.Sprintf("VC.ru %s", name)
+	if kind == KIND_AUTHOR {
+		title = fmt.Sprintf("VC.ru @%s", name)
+	}
+	return articles.GetFeed(SITE, title, listUrl(kind, name))
+}
@@ -3,9 +3,14 @@ package vkvideo
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 	"unicode"
 
@@ -25,8 +30,15 @@ var VALID_USERNAME_PATTERN = []*unicode.RangeTable{
 	{R16: []unicode.Range16{{'.', '.', 1}}},
 }
 
-var VALID_SKIP_BEFORE_PATTERN = []*unicode.RangeTable{
-	unicode.Digit,
+var VALID_LIVE_FILTER_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+}
+
+var descriptionTokenRe = regexp.MustCompile(`https?://[^\s<>"]+|\b(?:\d{1,2}:)?\d{1,2}:\d{2}\b`)
+
+var PARAMS = []utils.Param{
+	{Name: "username", Description: "Channel name from vk.com/video/@name", Example: "vkvideo", Path: true},
+	{Name: "live", Description: "Show only broadcasts or hide them", Options: []string{LIVE_FILTER_ONLY, LIVE_FILTER_EXCLUDE}},
 }
 
 type VkApi struct {
@@ -118,18 +130,57 @@ type VkVideoSectionJSON struct {
 	} `json:"response"`
 }
 
+type VkVideoEntryJSON struct {
+	Date          int    `json:"date"`
+	Description   string `json:"description"`
+	ID            int    `json:"id"`
+	OwnerID       int    `json:"owner_id"`
+	Title         string `json:"title"`
+	Duration      int    `json:"duration"`
+	Live          int    `json:"live"`
+	Upcoming      int    `json:"upcoming"`
+	LiveStatus    string `json:"live_status"`
+	LiveStartTime int    `json:"live_start_time"`
+}
+
 type VkVideoJSON struct {
 	Response struct {
-		Videos []struct {
-			Date        int    `json:"date"`
-			Description string `json:"description"`
-			ID          int    `json:"id"`
-			OwnerID     int    `json:"owner_id"`
-			Title       string `json:"title"`
-		} `json:"videos"`
+		Videos []VkVideoEntryJSON `json:"videos"`
 	} `json:"response"`
 }
 
+const (
+	LIVE_STATUS_NONE     = ""
+	LIVE_STATUS_LIVE     = "live"
+	LIVE_STATUS_UPCOMING = "upcoming"
+)
+
+const (
+	LIVE_FILTER_ALL     = ""
+	LIVE_FILTER_ONLY    = "only"
+	LIVE_FILTER_EXCLUDE = "exclude"
+)
+
+// GetLiveStatus tells apart running broadcasts and scheduled streams from
+// regular videos. Finished broadcasts are treated as regular videos.
+func GetLiveStatus(entry VkVideoEntryJSON) string {
+	switch entry.LiveStatus {
+	case "started":
+		return LIVE_STATUS_LIVE
+	case "waiting", "upcoming":
+		return LIVE_STATUS_UPCOMING
+	case "finished", "failed":
+		return LIVE_STATUS_NONE
+	}
+	if entry.Upcoming == 1 {
+		return LIVE_STATUS_UPCOMING
+	}
+	if entry.Live == 1 {
+		return LIVE_STATUS_LIVE
+	}
+	return LIVE_STATUS_NONE
+}
+
 func GetLatestVideosByUsername(token VkApiToken, username string) (VkVideoJSON, error) {
 	apiUrl := fmt.Sprintf("%s/method/catalog.getVideo", VK_API)
 	params := map[string]string{
@@ -178,45 +229,276 @@ func GetLatestVideosByUsername(token VkApiToken, username string) (VkVideoJSON,
 	return j, nil
 }
 
-func GetFeed(username string, token VkApiToken, skipBefore int) (string, error) {
-	videos, err := GetLatestVideosByUsername(token, username)
+// timecodeToSeconds converts "1:02:03" or "02:03" into seconds.
+func timecodeToSeconds(timecode string) int {
+	seconds := 0
+	for _, part := range strings.Split(timecode, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return -1
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
+
+func timecodeUrl(videoUrl string, seconds int) string {
+	h, m, s := seconds/3600, seconds%3600/60, seconds%60
+	t := ""
+	if h > 0 {
+		t += fmt.Sprintf("%dh", h)
+	}
+	if h > 0 || m > 0 {
+		t += fmt.Sprintf("%dm", m)
+	}
+	t += fmt.Sprintf("%ds", s)
+	return fmt.Sprintf("%s?t=%s", videoUrl, t)
+}
+
+func linkifyLine(line, videoUrl string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range descriptionTokenRe.FindAllStringIndex(line, -1) {
+		token := line[loc[0]:loc[1]]
+		end := loc[1]
+
+		var link string
+		if strings.HasPrefix(token, "http") {
+			trimmed := utils.TrimUrl(token)
+			end -= len(token) - len(trimmed)
+			token = trimmed
+			link = token
+		} else {
+			seconds := timecodeToSeconds(token)
+			if seconds < 0 {
+				continue
+			}
+			link = timecodeUrl(videoUrl, seconds)
+		}
+
+		b.WriteString(html.EscapeString(line[last:loc[0]]))
+		fmt.Fprintf(&b, `<a href="%s">%s</a>`, html.EscapeString(link), html.EscapeString(token))
+		last = end
+	}
+	b.WriteString(html.EscapeString(line[last:]))
+	return b.String()
+}
+
+// DescriptionToHtml renders a plain text VK description as HTML paragraphs
+// with clickable URLs and timecodes pointing into the video.
+func DescriptionToHtml(description, videoUrl string) string {
+	return utils.TextToHtml(description, func(line string) string {
+		return linkifyLine(line, videoUrl)
+	})
+}
+
+// FeedPath maps vk.com/video/@name and vkvideo.ru/@name to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	var username string
+	switch {
+	case (u.Host == "vk.com" || u.Host == "vk.ru") && len(parts) >= 2 && parts[0] == "video":
+		username = parts[1]
+	case u.Host == "vkvideo.ru" && len(parts) >= 1:
+		username = parts[0]
+	default:
+		return "", false
+	}
+
+	username, ok := strings.CutPrefix(username, "@")
+	if !ok {
+		return "", false
+	}
+	username = utils.StringsAllowlist(username, VALID_USERNAME_PATTERN)
+	if username == "" {
+		return "", false
+	}
+	return fmt.Sprintf("/vkvideo/%s", username), true
+}
+
+var videoIdRe = regexp.MustCompile(`^video(-?\d+)_\d+$`)
+
+type VkScreenNameJSON struct {
+	Response struct {
+		Groups []struct {
+			ScreenName string `json:"screen_name"`
+		} `json:"groups"`
+	} `json:"response"`
+}
+
+type VkUserScreenNameJSON struct {
+	Response []struct {
+		ScreenName string `json:"screen_name"`
+	} `json:"response"`
+}
+
+// GetScreenName looks up the short name of a community (negative id) or
+// user (positive id).
+func GetScreenName(token VkApiToken, ownerId int) (string, error) {
+	method := "users.get"
+	params := map[string]string{
+		"v":            "5.241",
+		"client_id":    "6287487",
+		"access_token": token.Token,
+	}
+	if ownerId < 0 {
+		method = "groups.getById"
+		params["group_ids"] = strconv.Itoa(-ownerId)
+	} else {
+		params["user_ids"] = strconv.Itoa(ownerId)
+		params["fields"] = "screen_name"
+	}
+
+	apiUrl := fmt.Sprintf("%s/method/%s", VK_API, method)
+
+	req, err := http.NewRequest("POST", apiUrl, nil)
 	if err != nil {
+		log.Println(err)
 		return "", err
 	}
 
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+
+	q := req.URL.Query()
+	for key, value := range params {
+		q.Add(key, value)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+
+	var screenName string
+	if ownerId < 0 {
+		var j VkScreenNameJSON
+		err = json.Unmarshal(body, &j)
+		if err != nil {
+			return "", err
+		}
+		if len(j.Response.Groups) > 0 {
+			screenName = j.Response.Groups[0].ScreenName
+		}
+	} else {
+		var j VkUserScreenNameJSON
+		err = json.Unmarshal(body, &j)
+		if err != nil {
+			return "", err
+		}
+		if len(j.Response) > 0 {
+			screenName = j.Response[0].ScreenName
+		}
+	}
+
+	if screenName == "" {
+		return "", fmt.Errorf("no screen name for owner %d", ownerId)
+	}
+	return screenName, nil
+}
+
+// LookupFeedPath finds the owner of a vk.com/video-1_2 style link, the
+// token is only requested for links it recognises.
+func LookupFeedPath(getToken func() (VkApiToken, error), u *url.URL) (string, bool, error) {
+	if u.Host != "vk.com" && u.Host != "vk.ru" && u.Host != "vkvideo.ru" {
+		return "", false, nil
+	}
+
+	videoId := strings.Trim(u.Path, "/")
+	// links opened from a channel page keep the video in the query
+	if z := u.Query().Get("z"); z != "" {
+		videoId = strings.SplitN(z, "/", 2)[0]
+	}
+
+	match := videoIdRe.FindStringSubmatch(videoId)
+	if match == nil {
+		return "", false, nil
+	}
+
+	ownerId, err := strconv.Atoi(match[1])
+	if err != nil {
+		return "", true, err
+	}
+
+	token, err := getToken()
+	if err != nil {
+		return "", true, err
+	}
+
+	screenName, err := GetScreenName(token, ownerId)
+	if err != nil {
+		return "", true, err
+	}
+	return fmt.Sprintf("/vkvideo/%s", screenName), true, nil
+}
+
+func GetFeed(username string, token VkApiToken, liveFilter string) (*utils.Feed, error) {
+	videos, err := GetLatestVideosByUsername(token, username)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(videos.Response.Videos) == 0 {
-		return "", fmt.Errorf("no videos")
+		return nil, fmt.Errorf("no videos")
 	}
 
-	feed := &feeds.Feed{
+	feed := utils.NewFeed(&feeds.Feed{
 		Title: fmt.Sprintf("VK Video @%s", username),
 		Link: &feeds.Link{
 			Href: fmt.Sprintf("https://vk.com/video/@%s", username),
 		},
 		Description: fmt.Sprintf("Лента RSS VK Video @%s", username),
-	}
+	})
 
 	seenSet := mapset.NewSet[string]()
 	for _, entry := range videos.Response.Videos {
-		if skipBefore > entry.Date {
+		liveStatus := GetLiveStatus(entry)
+		if liveFilter == LIVE_FILTER_ONLY && liveStatus == LIVE_STATUS_NONE {
+			continue
+		}
+		if liveFilter == LIVE_FILTER_EXCLUDE && liveStatus != LIVE_STATUS_NONE {
 			continue
 		}
+
 		videoUrl := fmt.Sprintf("https://vk.com/video%d_%d", entry.OwnerID, entry.ID)
 
 		if seenSet.Contains(videoUrl) {
 			continue
 		}
 
+		title := entry.Title
+		description := DescriptionToHtml(entry.Description, videoUrl)
+		switch liveStatus {
+		case LIVE_STATUS_LIVE:
+			title = fmt.Sprintf("[LIVE] %s", title)
+		case LIVE_STATUS_UPCOMING:
+			title = fmt.Sprintf("[UPCOMING] %s", title)
+			if entry.LiveStartTime > 0 {
+				startTime := time.Unix(int64(entry.LiveStartTime), 0).UTC().Format(time.RFC1123Z)
+				description = fmt.Sprintf("<p>Начало трансляции: %s</p>%s", startTime, description)
+			}
+		}
+
 		seenSet.Add(videoUrl)
 		feed.Items = append(feed.Items, &feeds.Item{
-			Title:       entry.Title,
+			Title:       title,
 			Link:        &feeds.Link{Href: videoUrl},
-			Description: entry.Description,
+			Description: description,
 			Created:     time.Unix(int64(entry.Date), 0),
 			Id:          videoUrl,
 		})
-
+		feed.SetDuration(videoUrl, time.Duration(entry.Duration)*time.Second)
 	}
 
-	return feed.ToRss()
+	return feed, nil
 }
@@ -0,0 +1,332 @@
+package vkwall
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/vkvideo"
+)
+
+const POSTS_COUNT = "20"
+
+var VALID_DOMAIN_PATTERN = []*unicode.RangeTable{
+	unicode.Letter,
+	unicode.Digit,
+	{R16: []unicode.Range16{{'_', '_', 1}}},
+	{R16: []unicode.Range16{{'.', '.', 1}}},
+}
+
+var PARAMS = []utils.Param{
+	{Name: "domain", Description: "Community or user short name from vk.com/name", Example: "team", Path: true},
+	{Name: "no_ads", Description: "Hide posts marked as advertising", Options: []string{"1"}},
+	{Name: "no_pinned", Description: "Hide the pinned post", Options: []string{"1"}},
+}
+
+// first path segments on vk.com that are not communities or users
+var RESERVED_PATHS = []string{
+	"video", "feed", "im", "search", "audio", "audios", "music", "friends",
+	"groups", "clips", "market", "apps", "photos", "albums", "docs", "away.php",
+}
+
+var wallPostRe = regexp.MustCompile(`^wall(-?)(\d+)_\d+$`)
+
+// links to single videos, photos and other objects, not to a wall
+var objectRe = regexp.MustCompile(`^[a-z]+-?\d+_\d+$`)
+
+type VkPhotoJSON struct {
+	Sizes []struct {
+		URL    string `json:"url"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	} `json:"sizes"`
+}
+
+type VkAttachmentJSON struct {
+	Type  string      `json:"type"`
+	Photo VkPhotoJSON `json:"photo"`
+	Link  struct {
+		URL         string      `json:"url"`
+		Title       string      `json:"title"`
+		Description string      `json:"description"`
+		Photo       VkPhotoJSON `json:"photo"`
+	} `json:"link"`
+	Video struct {
+		ID      int    `json:"id"`
+		OwnerID int    `json:"owner_id"`
+		Title   string `json:"title"`
+		Image   []struct {
+			URL   string `json:"url"`
+			Width int    `json:"width"`
+		} `json:"image"`
+	} `json:"video"`
+}
+
+type VkPostJSON struct {
+	ID          int                `json:"id"`
+	OwnerID     int                `json:"owner_id"`
+	FromID      int                `json:"from_id"`
+	Date        int                `json:"date"`
+	Text        string             `json:"text"`
+	IsPinned    int                `json:"is_pinned"`
+	MarkedAsAds int                `json:"marked_as_ads"`
+	Attachments []VkAttachmentJSON `json:"attachments"`
+	CopyHistory []VkPostJSON       `json:"copy_history"`
+}
+
+type VkWallJSON struct {
+	Response struct {
+		Items    []VkPostJSON `json:"items"`
+		Profiles []struct {
+			ID        int    `json:"id"`
+			FirstName string `json:"first_name"`
+			LastName  string `json:"last_name"`
+		} `json:"profiles"`
+		Groups []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"groups"`
+	} `json:"response"`
+	Error struct {
+		ErrorCode int    `json:"error_code"`
+		ErrorMsg  string `json:"error_msg"`
+	} `json:"error"`
+}
+
+func GetLatestPostsByDomain(token vkvideo.VkApiToken, domain string) (VkWallJSON, error) {
+	apiUrl := fmt.Sprintf("%s/method/wall.get", vkvideo.VK_API)
+	params := map[string]string{
+		"v":            "5.241",
+		"client_id":    "6287487",
+		"access_token": token.Token,
+		"domain":       domain,
+		"count":        POSTS_COUNT,
+		"extended":     "1",
+	}
+
+	req, err := http.NewRequest("POST", apiUrl, nil)
+	if err != nil {
+		log.Println(err)
+		return VkWallJSON{}, err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+
+	q := req.URL.Query()
+	for key, value := range params {
+		q.Add(key, value)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return VkWallJSON{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return VkWallJSON{}, err
+	}
+
+	var j VkWallJSON
+	err = json.Unmarshal(body, &j)
+	if err != nil {
+		return VkWallJSON{}, err
+	}
+
+	if j.Error.ErrorCode != 0 {
+		return VkWallJSON{}, fmt.Errorf("wall.get: %d %s", j.Error.ErrorCode, j.Error.ErrorMsg)
+	}
+
+	return j, nil
+}
+
+// FeedPath maps vk.com/name and vk.com/wall-1_2 to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	if u.Host != "vk.com" && u.Host != "vk.ru" {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 1 || parts[0] == "" || slices.Contains(RESERVED_PATHS, parts[0]) {
+		return "", false
+	}
+
+	if match := wallPostRe.FindStringSubmatch(parts[0]); match != nil {
+		if match[1] == "-" {
+			return fmt.Sprintf("/vkwall/club%s", match[2]), true
+		}
+		return fmt.Sprintf("/vkwall/id%s", match[2]), true
+	}
+
+	domain := utils.StringsAllowlist(parts[0], VALID_DOMAIN_PATTERN)
+	if domain == "" || domain != parts[0] || objectRe.MatchString(domain) {
+		return "", false
+	}
+	return fmt.Sprintf("/vkwall/%s", domain), true
+}
+
+func postUrl(post VkPostJSON) string {
+	return fmt.Sprintf("https://vk.com/wall%d_%d", post.OwnerID, post.ID)
+}
+
+func largestPhoto(photo VkPhotoJSON) string {
+	var url string
+	width := -1
+	for _, size := range photo.Sizes {
+		if size.Width > width {
+			url, width = size.URL, size.Width
+		}
+	}
+	return url
+}
+
+func renderAttachments(b *strings.Builder, attachments []VkAttachmentJSON) {
+	for _, attachment := range attachments {
+		switch attachment.Type {
+		case "photo":
+			if src := largestPhoto(attachment.Photo); src != "" {
+				fmt.Fprintf(b, `<p><img src="%s"></p>`, html.EscapeString(src))
+			}
+		case "link":
+			link := attachment.Link
+			title := link.Title
+			if title == "" {
+				title = link.URL
+			}
+			fmt.Fprintf(b, `<p><a href="%s">%s</a>`, html.EscapeString(link.URL), html.EscapeString(title))
+			if link.Description != "" {
+				fmt.Fprintf(b, "<br>%s", html.EscapeString(link.Description))
+			}
+			b.WriteString("</p>")
+			if src := largestPhoto(link.Photo); src != "" {
+				fmt.Fprintf(b, `<p><img src="%s"></p>`, html.EscapeString(src))
+			}
+		case "video":
+			video := attachment.Video
+			videoUrl := fmt.Sprintf("https://vk.com/video%d_%d", video.OwnerID, video.ID)
+			fmt.Fprintf(b, `<p><a href="%s">%s</a></p>`, html.EscapeString(videoUrl), html.EscapeString(video.Title))
+			if len(video.Image) > 0 {
+				image := video.Image[len(video.Image)-1].URL
+				fmt.Fprintf(b, `<p><a href="%s"><img src="%s"></a></p>`, html.EscapeString(videoUrl), html.EscapeString(image))
+			}
+		}
+	}
+}
+
+func postDescription(post VkPostJSON, names map[int]string) string {
+	var b strings.Builder
+	b.WriteString(utils.TextToHtml(post.Text, nil))
+	renderAttachments(&b, post.Attachments)
+
+	for _, repost := range post.CopyHistory {
+		name := names[repost.OwnerID]
+		if name == "" {
+			name = fmt.Sprintf("%d", repost.OwnerID)
+		}
+		fmt.Fprintf(&b, `<p>Репост из <a href="%s">%s</a></p><blockquote>`,
+			html.EscapeString(postUrl(repost)), html.EscapeString(name))
+		b.WriteString(utils.TextToHtml(repost.Text, nil))
+		renderAttachments(&b, repost.Attachments)
+		b.WriteString("</blockquote>")
+	}
+
+	return b.String()
+}
+
+func postTitle(post VkPostJSON) string {
+	for _, line := range strings.Split(post.Text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return utils.Truncate(line, 100)
+		}
+	}
+	for _, repost := range post.CopyHistory {
+		for _, line := range strings.Split(repost.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				return fmt.Sprintf("Репост: %s", utils.Truncate(line, 100))
+			}
+		}
+		return "Репост"
+	}
+	return "Запись"
+}
+
+func GetFeed(domain string, token vkvideo.VkApiToken, noAds bool, noPinned bool) (*utils.Feed, error) {
+	wall, err := GetLatestPostsByDomain(token, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wall.Response.Items) == 0 {
+		return nil, fmt.Errorf("no posts")
+	}
+
+	// owner ids of communities are negative
+	names := map[int]string{}
+	for _, profile := range wall.Response.Profiles {
+		names[profile.ID] = strings.TrimSpace(profile.FirstName + " " + profile.LastName)
+	}
+	for _, group := range wall.Response.Groups {
+		names[-group.ID] = group.Name
+	}
+
+	title := names[wall.Response.Items[0].OwnerID]
+	if title == "" {
+		title = domain
+	}
+
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: fmt.Sprintf("VK @%s", title),
+		Link: &feeds.Link{
+			Href: fmt.Sprintf("https://vk.com/%s", domain),
+		},
+		Description: fmt.Sprintf("Лента RSS стены VK @%s", domain),
+	})
+
+	seenSet := mapset.NewSet[string]()
+	for _, post := range wall.Response.Items {
+		if noAds && post.MarkedAsAds == 1 {
+			continue
+		}
+		if noPinned && post.IsPinned == 1 {
+			continue
+		}
+
+		url := postUrl(post)
+
+		if seenSet.Contains(url) {
+			continue
+		}
+
+		item := &feeds.Item{
+			Title:       postTitle(post),
+			Link:        &feeds.Link{Href: url},
+			Description: postDescription(post, names),
+			Created:     time.Unix(int64(post.Date), 0),
+			Id:          url,
+		}
+		if name := names[post.FromID]; name != "" && post.FromID != post.OwnerID {
+			item.Author = &feeds.Author{Name: name}
+		}
+
+		seenSet.Add(url)
+		feed.Items = append(feed.Items, item)
+	}
+
+	return feed, nil
+}
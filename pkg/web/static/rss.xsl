@@ -0,0 +1,33 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+<xsl:output method="html" encoding="UTF-8" indent="yes"/>
+<xsl:template match="/rss/channel">
+<html>
+<head>
+<meta charset="utf-8"/>
+<meta name="viewport" content="width=device-width, initial-scale=1"/>
+<title><xsl:value-of select="title"/></title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 60rem; margin: 0 auto; padding: 1rem; color: #222; }
+a { color: #0b5cad; }
+.note { background: #fff8e1; padding: .5rem 1rem; }
+.item { border-top: 1px solid #ddd; padding: 1rem 0; }
+.item img { max-width: 100%; height: auto; }
+.meta { color: #777; font-size: .9rem; }
+</style>
+</head>
+<body>
+<p class="note">This is an RSS feed. Copy the address from the address bar into your feed reader to subscribe.</p>
+<h1><a href="{link}"><xsl:value-of select="title"/></a></h1>
+<p><xsl:value-of select="description"/></p>
+<xsl:for-each select="item">
+<div class="item">
+<h3><a href="{link}"><xsl:value-of select="title"/></a></h3>
+<p class="meta"><xsl:value-of select="pubDate"/><xsl:if test="category"> · <xsl:value-of select="category"/></xsl:if></p>
+<div><xsl:value-of select="description" disable-output-escaping="yes"/></div>
+</div>
+</xsl:for-each>
+</body>
+</html>
+</xsl:template>
+</xsl:stylesheet>
@@ -0,0 +1,208 @@
+package web
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/bridge"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/filter"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+//go:embed static/rss.xsl
+var rssStylesheet []byte
+
+const STYLESHEET_PATH = "/rss.xsl"
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+var imgSrcRe = regexp.MustCompile(`<img[^>]+src="([^"]+)"`)
+
+type FeedPage struct {
+	Title       string
+	Description string
+	Link        string
+	FeedUrl     string
+	Items       []ItemView
+	Error       string
+}
+
+type ItemView struct {
+	Title       string
+	Link        string
+	Date        string
+	Duration    string
+	Category    string
+	Image       string
+	Description template.HTML
+}
+
+// BASE_URL_ENV is the public address of the bridge, e.g.
+// https://rss.example.com, the request headers are only trusted without it.
+const BASE_URL_ENV = "BASE_URL"
+
+// BaseUrl is the address the bridge is reachable at, as seen by the client.
+func BaseUrl(c *gin.Context) string {
+	if baseUrl := strings.TrimSpace(os.Getenv(BASE_URL_ENV)); baseUrl != "" {
+		return strings.TrimSuffix(baseUrl, "/")
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+}
+
+// itemImage picks an image enclosure or the first image of the description
+// as the item thumbnail.
+func itemImage(enclosureType, enclosureUrl, description string) string {
+	if strings.HasPrefix(enclosureType, "image/") {
+		return enclosureUrl
+	}
+	if match := imgSrcRe.FindStringSubmatch(description); match != nil {
+		return html.UnescapeString(match[1])
+	}
+	return ""
+}
+
+func NewFeedPage(feed *utils.Feed, feedUrl string) FeedPage {
+	page := FeedPage{
+		Title:       feed.Title,
+		Description: feed.Description,
+		FeedUrl:     feedUrl,
+	}
+	if feed.Link != nil {
+		page.Link = feed.Link.Href
+	}
+
+	for _, item := range feed.Items {
+		view := ItemView{
+			Title:    item.Title,
+			Category: feed.Categories[item.Id],
+		}
+		if item.Link != nil {
+			view.Link = item.Link.Href
+		}
+		if !item.Created.IsZero() {
+			view.Date = item.Created.Format(time.RFC1123Z)
+		}
+		if duration := feed.Durations[item.Id]; duration > 0 {
+			view.Duration = duration.String()
+		}
+
+		description := item.Description
+		if item.Content != "" {
+			description = item.Content
+		}
+		// bridges pass through HTML from the sources, never trust it as is
+		sanitized, err := utils.SanitizeHtml(description, view.Link)
+		if err != nil {
+			log.Println(err)
+			sanitized = template.HTMLEscapeString(utils.HtmlToText(description))
+		}
+		view.Description = template.HTML(sanitized)
+
+		if item.Enclosure != nil {
+			view.Image = itemImage(item.Enclosure.Type, item.Enclosure.Url, sanitized)
+		} else {
+			view.Image = itemImage("", "", sanitized)
+		}
+
+		page.Items = append(page.Items, view)
+	}
+	return page
+}
+
+func render(c *gin.Context, status int, name string, data any) {
+	var b bytes.Buffer
+	err := templates.ExecuteTemplate(&b, name, data)
+	if err != nil {
+		log.Println(err)
+		c.String(http.StatusInternalServerError, "error")
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", b.Bytes())
+}
+
+func RenderFeed(c *gin.Context, page FeedPage) {
+	status := http.StatusOK
+	if page.Error != "" {
+		status = http.StatusBadRequest
+	}
+	render(c, status, "feed.html", page)
+}
+
+func StylesheetRoute(c *gin.Context) {
+	c.Data(http.StatusOK, "text/xsl; charset=utf-8", rssStylesheet)
+}
+
+// FeedUrl is the absolute URL of the current request without the format
+// parameter, i.e. the URL to subscribe to.
+func FeedUrl(c *gin.Context) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Del("format")
+	u.RawQuery = query.Encode()
+	return BaseUrl(c) + u.RequestURI()
+}
+
+func IndexRoute(bridges []bridge.Bridge) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		render(c, http.StatusOK, "index.html", gin.H{
+			"Bridges":      bridges,
+			"FilterParams": filter.PARAMS,
+		})
+	}
+}
+
+// PreviewRoute builds the feed URL from the index page form and shows the
+// items the bridge produces for it.
+func PreviewRoute(bridges []bridge.Bridge) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := strings.TrimSpace(c.Query("bridge"))
+
+		var selected *bridge.Bridge
+		for i := range bridges {
+			if bridges[i].Name == name {
+				selected = &bridges[i]
+				break
+			}
+		}
+		if selected == nil {
+			RenderFeed(c, FeedPage{Title: "Preview", Error: fmt.Sprintf("unknown bridge %s", name)})
+			return
+		}
+
+		path, err := selected.BuildPath(c.Request.URL.Query())
+		if err != nil {
+			RenderFeed(c, FeedPage{Title: selected.Title, Error: err.Error()})
+			return
+		}
+
+		feed, err := bridge.FetchFeed(bridges, path)
+		if err != nil {
+			log.Println(err)
+			RenderFeed(c, FeedPage{Title: selected.Title, Error: err.Error()})
+			return
+		}
+
+		RenderFeed(c, NewFeedPage(feed, BaseUrl(c)+path))
+	}
+}
@@ -0,0 +1,283 @@
+package yandexmusic
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/gorilla/feeds"
+	"github.com/kiberdruzhinnik/go-rss-bridge/pkg/utils"
+)
+
+// https://api.music.yandex.net/albums/12345/with-tracks
+
+const YANDEX_MUSIC_API = "https://api.music.yandex.net"
+const YANDEX_MUSIC_SITE = "https://music.yandex.ru"
+
+// TOKEN_ENV is an optional OAuth token for podcasts that need an account
+const TOKEN_ENV = "YANDEX_MUSIC_TOKEN"
+
+// salt of the signature in download links
+const DOWNLOAD_SALT = "XGRlBW9FXlekgbPrRHuSiA"
+
+const COVER_SIZE = "400x400"
+
+var VALID_ID_PATTERN = []*unicode.RangeTable{
+	unicode.Digit,
+}
+
+var PARAMS = []utils.Param{
+	{Name: "album_id", Description: "Podcast id from music.yandex.ru/album/id", Example: "9294859", Path: true},
+}
+
+type YandexTrackJSON struct {
+	ID               json.Number `json:"id"`
+	Title            string      `json:"title"`
+	DurationMs       int         `json:"durationMs"`
+	PubDate          string      `json:"pubDate"`
+	ShortDescription string      `json:"shortDescription"`
+	Available        bool        `json:"available"`
+}
+
+type YandexAlbumJSON struct {
+	Result struct {
+		ID          int    `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		CoverUri    string `json:"coverUri"`
+		Artists     []struct {
+			Name string `json:"name"`
+		} `json:"artists"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		Volumes [][]YandexTrackJSON `json:"volumes"`
+	} `json:"result"`
+	Error struct {
+		Name    string `json:"name"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type YandexDownloadInfoJSON struct {
+	Result []struct {
+		Codec           string `json:"codec"`
+		BitrateInKbps   int    `json:"bitrateInKbps"`
+		DownloadInfoUrl string `json:"downloadInfoUrl"`
+	} `json:"result"`
+}
+
+type DownloadInfoXML struct {
+	Host string `xml:"host"`
+	Path string `xml:"path"`
+	Ts   string `xml:"ts"`
+	S    string `xml:"s"`
+}
+
+func get(apiUrl string) ([]byte, error) {
+	req, err := http.NewRequest("GET", apiUrl, nil)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", utils.USER_AGENT)
+	if token := os.Getenv(TOKEN_ENV); token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("OAuth %s", token))
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got status %d for %s", resp.StatusCode, apiUrl)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return body, nil
+}
+
+func GetAlbum(albumId string) (YandexAlbumJSON, error) {
+	body, err := get(fmt.Sprintf("%s/albums/%s/with-tracks", YANDEX_MUSIC_API, albumId))
+	if err != nil {
+		return YandexAlbumJSON{}, err
+	}
+
+	var j YandexAlbumJSON
+	err = json.Unmarshal(body, &j)
+	if err != nil {
+		return YandexAlbumJSON{}, err
+	}
+
+	if j.Error.Name != "" {
+		return YandexAlbumJSON{}, fmt.Errorf("album %s: %s %s", albumId, j.Error.Name, j.Error.Message)
+	}
+
+	return j, nil
+}
+
+// GetTrackUrl signs a fresh link to the mp3 of a track, links expire so
+// feeds point at the bridge and get redirected here.
+func GetTrackUrl(trackId string) (string, error) {
+	body, err := get(fmt.Sprintf("%s/tracks/%s/download-info", YANDEX_MUSIC_API, trackId))
+	if err != nil {
+		return "", err
+	}
+
+	var j YandexDownloadInfoJSON
+	err = json.Unmarshal(body, &j)
+	if err != nil {
+		return "", err
+	}
+
+	var infoUrl string
+	bitrate := -1
+	for _, info := range j.Result {
+		if info.Codec == "mp3" && info.BitrateInKbps > bitrate {
+			infoUrl, bitrate = info.DownloadInfoUrl, info.BitrateInKbps
+		}
+	}
+	if infoUrl == "" {
+		return "", fmt.Errorf("no mp3 for track %s", trackId)
+	}
+
+	body, err = get(infoUrl)
+	if err != nil {
+		return "", err
+	}
+
+	var info DownloadInfoXML
+	err = xml.Unmarshal(body, &info)
+	if err != nil {
+		return "", err
+	}
+
+	sum := md5.Sum([]byte(DOWNLOAD_SALT + strings.TrimPrefix(info.Path, "/") + info.S))
+	return fmt.Sprintf("https://%s/get-mp3/%s/%s%s", info.Host, hex.EncodeToString(sum[:]), info.Ts, info.Path), nil
+}
+
+// FeedPath maps music.yandex.ru/album/<id> to the bridge route.
+func FeedPath(u *url.URL) (string, bool) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if (u.Host != "music.yandex.ru" && u.Host != "music.yandex.com") || len(parts) < 2 || parts[0] != "album" {
+		return "", false
+	}
+
+	albumId := utils.StringsAllowlist(parts[1], VALID_ID_PATTERN)
+	if albumId == "" || albumId != parts[1] {
+		return "", false
+	}
+	return fmt.Sprintf("/yandex-music/%s", albumId), true
+}
+
+func coverUrl(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	return "https://" + strings.ReplaceAll(uri, "%%", COVER_SIZE)
+}
+
+// GetFeed builds a podcast feed of an album, trackUrl gives the enclosure
+// address of a track id.
+func GetFeed(albumId string, trackUrl func(trackId string) string) (*utils.Feed, error) {
+	album, err := GetAlbum(albumId)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []YandexTrackJSON
+	for _, volume := range album.Result.Volumes {
+		tracks = append(tracks, volume...)
+	}
+
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no episodes")
+	}
+
+	title := album.Result.Title
+	if title == "" {
+		title = albumId
+	}
+
+	var author string
+	if len(album.Result.Artists) > 0 {
+		author = album.Result.Artists[0].Name
+	} else if len(album.Result.Labels) > 0 {
+		author = album.Result.Labels[0].Name
+	}
+
+	albumUrl := fmt.Sprintf("%s/album/%s", YANDEX_MUSIC_SITE, albumId)
+	feed := utils.NewFeed(&feeds.Feed{
+		Title: title,
+		Link: &feeds.Link{
+			Href: albumUrl,
+		},
+		Description: album.Result.Description,
+	})
+	if feed.Description == "" {
+		feed.Description = fmt.Sprintf("Подкаст %s в Яндекс Музыке", title)
+	}
+	if author != "" {
+		feed.Author = &feeds.Author{Name: author}
+	}
+	if cover := coverUrl(album.Result.CoverUri); cover != "" {
+		feed.Image = &feeds.Image{Url: cover, Title: title, Link: albumUrl}
+	}
+	feed.Podcast = true
+
+	seenSet := mapset.NewSet[string]()
+	for _, track := range tracks {
+		trackId := track.ID.String()
+		episodeUrl := fmt.Sprintf("%s/track/%s", albumUrl, trackId)
+
+		if !track.Available || seenSet.Contains(episodeUrl) {
+			continue
+		}
+
+		created, err := time.Parse(time.RFC3339, track.PubDate)
+		if err != nil {
+			log.Println(err)
+		}
+
+		seenSet.Add(episodeUrl)
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       track.Title,
+			Link:        &feeds.Link{Href: episodeUrl},
+			Description: utils.TextToHtml(track.ShortDescription, nil),
+			Created:     created,
+			Id:          episodeUrl,
+			Enclosure: &feeds.Enclosure{
+				Url:    trackUrl(trackId),
+				Length: "0",
+				Type:   "audio/mpeg",
+			},
+		})
+		feed.SetDuration(episodeUrl, time.Duration(track.DurationMs)*time.Millisecond)
+	}
+
+	feed.Sort(func(a, b *feeds.Item) bool {
+		return a.Created.After(b.Created)
+	})
+
+	return feed, nil
+}